@@ -0,0 +1,89 @@
+package priority
+
+import (
+	"context"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+)
+
+// Prioritized can be used to send an item along with its priority
+// through a channel
+type Prioritized struct {
+	Item     interface{}
+	Priority int
+}
+
+// waitSignal will block until a signal is received, it will return false if
+// the done channel is closed or the queue has been closed; if the signal
+// channel is closed because the queue was resized, it'll return true
+func waitSignal(getSignal func() <-chan struct{}, done <-chan struct{}) bool {
+	signal := getSignal()
+	if signal == nil {
+		return false
+	}
+	select {
+	case <-done:
+		return false
+	case _, ok := <-signal:
+		if !ok {
+			return getSignal() != nil
+		}
+	}
+	return true
+}
+
+// Inbound can be used to pump items from a channel into a priority queue, items
+// are read from the channel until it's closed or an item can't be enqueued because
+// the queue has been closed. While the queue is full, items will no longer be read
+// from the channel (backpressure). The returned channel will be closed once the pump
+// has stopped, keep in mind that if the queue is closed, the item being enqueued
+// will be discarded
+func Inbound(queue interface {
+	PriorityEnqueuer
+	goqueue.Event
+}, ch <-chan Prioritized) <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		for item := range ch {
+			for queue.PriorityEnqueue(item.Item, item.Priority) {
+				if !waitSignal(queue.GetSignalOut, nil) {
+					return
+				}
+			}
+		}
+	}()
+	return stopped
+}
+
+// Outbound can be used to pump items from a priority queue into the returned
+// channel, items are dequeued in priority order and only once they can be sent
+// (at most one item is held while waiting for a receiver). The returned channel
+// will be closed when the context is done or the queue is closed, keep in mind
+// that if the context is done while an item is held, that item is discarded
+func Outbound(ctx context.Context, queue interface {
+	goqueue.Dequeuer
+	goqueue.Event
+}) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+
+		for {
+			item, underflow := queue.Dequeue()
+			if underflow {
+				if !waitSignal(queue.GetSignalIn, ctx.Done()) {
+					return
+				}
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- item:
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,332 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	server "github.com/antonio-alexander/go-queue-priority/server"
+	finite "github.com/antonio-alexander/go-queue/finite"
+)
+
+type client struct {
+	address      string
+	name         string
+	client       *http.Client
+	config       Configuration
+	decoder      Decoder
+	priorityFunc goqueuepriority.PriorityFunc
+}
+
+// New can be used to create a client for the queue with the given name served
+// at address (e.g. http://localhost:8080). The optional parameters can be used
+// to further configure the client:
+//
+//   - Configuration: configures timeouts, how long to wait when dequeuing and
+//     a callback for errors
+//
+//   - *http.Client: the http client used to make requests
+//
+//   - goqueuepriority.PriorityFunc: determines the priority of items enqueued using
+//     Enqueue, otherwise items that implement goqueuepriority.Prioritizer provide their own
+//     priority (items enqueued using PriorityEnqueue without a priority have the
+//     default priority)
+//
+//   - Decoder: decodes items received from the server, otherwise items are
+//     decoded into an empty interface
+//
+// Items are sent and received as plain JSON, so items can be enqueued and
+// dequeued interchangeably by the client, the server's REST API and local
+// consumers of the served queue
+func New(address, name string, parameters ...interface{}) interface {
+	goqueue.Dequeuer
+	goqueue.Enqueuer
+	goqueue.Peeker
+	goqueue.Length
+	goqueuepriority.PriorityEnqueuer
+	goqueuepriority.WrapperPeeker
+	goqueuepriority.Reprioritizer
+	finite.Capacity
+	finite.Resizer
+} {
+	c := &client{
+		address: strings.TrimSuffix(address, "/"),
+		name:    name,
+		client:  http.DefaultClient,
+	}
+	for _, parameter := range parameters {
+		switch p := parameter.(type) {
+		case Configuration:
+			c.config = p
+		case *Configuration:
+			c.config = *p
+		case *http.Client:
+			c.client = p
+		case Decoder:
+			c.decoder = p
+		case func(raw json.RawMessage) (interface{}, error):
+			c.decoder = p
+		case goqueuepriority.PriorityFunc:
+			c.priorityFunc = p
+		case func(item interface{}) int:
+			c.priorityFunc = p
+		}
+	}
+	if c.config.Timeout <= 0 {
+		c.config.Timeout = DefaultTimeout
+	}
+	return c
+}
+
+func (c *client) error(err error) {
+	if c.config.OnError != nil {
+		c.config.OnError(err)
+	}
+}
+
+// do will execute the operation on the queue and decode the response, if
+// the operation fails the error callback is executed and false is returned
+func (c *client) do(method, operation string, query url.Values, request, response interface{}) bool {
+	var body bytes.Buffer
+
+	if request != nil {
+		if err := json.NewEncoder(&body).Encode(request); err != nil {
+			c.error(err)
+			return false
+		}
+	}
+	uri := c.address + server.PathQueues + url.PathEscape(c.name) + "/" + operation
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout+c.config.Wait)
+	defer cancel()
+	httpRequest, err := http.NewRequestWithContext(ctx, method, uri, &body)
+	if err != nil {
+		c.error(err)
+		return false
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpResponse, err := c.client.Do(httpRequest)
+	if err != nil {
+		c.error(err)
+		return false
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		var e server.ErrorResponse
+
+		_ = json.NewDecoder(httpResponse.Body).Decode(&e)
+		c.error(fmt.Errorf("%w: %d %s", ErrUnexpectedStatus, httpResponse.StatusCode, e.Error))
+		return false
+	}
+	if err := json.NewDecoder(httpResponse.Body).Decode(response); err != nil {
+		c.error(err)
+		return false
+	}
+	return true
+}
+
+func (c *client) wait() url.Values {
+	if c.config.Wait <= 0 {
+		return nil
+	}
+	return url.Values{server.ParameterWait: []string{c.config.Wait.String()}}
+}
+
+func (c *client) marshal(item interface{}) (json.RawMessage, error) {
+	return json.Marshal(item)
+}
+
+func (c *client) unmarshal(raw json.RawMessage) interface{} {
+	var item interface{}
+
+	if c.decoder != nil {
+		item, err := c.decoder(raw)
+		if err != nil {
+			c.error(err)
+		}
+		return item
+	}
+	if err := json.Unmarshal(raw, &item); err != nil {
+		c.error(err)
+	}
+	return item
+}
+
+func (c *client) items(operation string, query url.Values, request interface{}) []interface{} {
+	var response server.ItemsResponse
+
+	method := http.MethodPost
+	if operation == server.OperationPeek {
+		method = http.MethodGet
+	}
+	if !c.do(method, operation, query, request, &response) || len(response.Items) == 0 {
+		return nil
+	}
+	items := make([]interface{}, 0, len(response.Items))
+	for _, raw := range response.Items {
+		items = append(items, c.unmarshal(raw))
+	}
+	return items
+}
+
+func (c *client) Dequeue() (interface{}, bool) {
+	var response server.DequeueResponse
+
+	if !c.do(http.MethodPost, server.OperationDequeue, c.wait(), nil, &response) || response.Underflow {
+		return nil, true
+	}
+	return c.unmarshal(response.Item), false
+}
+
+func (c *client) DequeueMultiple(n int) []interface{} {
+	query := c.wait()
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set(server.ParameterN, strconv.Itoa(n))
+	return c.items(server.OperationDequeueMultiple, query, nil)
+}
+
+func (c *client) Flush() []interface{} {
+	return c.items(server.OperationFlush, nil, nil)
+}
+
+func (c *client) Enqueue(item interface{}) bool {
+	return c.PriorityEnqueue(item, goqueuepriority.ItemPriority(item, c.priorityFunc))
+}
+
+func (c *client) EnqueueMultiple(items []interface{}) ([]interface{}, bool) {
+	return c.PriorityEnqueueMultiple(items, goqueuepriority.ItemPriorities(items, c.priorityFunc)...)
+}
+
+func (c *client) PriorityEnqueue(item interface{}, priorities ...int) bool {
+	var response server.EnqueueResponse
+
+	raw, err := c.marshal(item)
+	if err != nil {
+		c.error(err)
+		return true
+	}
+	request := server.EnqueueRequest{Item: raw, Priority: goqueuepriority.DefaultPriority}
+	if len(priorities) > 0 {
+		request.Priority = priorities[0]
+	}
+	if !c.do(http.MethodPost, server.OperationEnqueue, nil, request, &response) {
+		return true
+	}
+	return response.Overflow
+}
+
+func (c *client) PriorityEnqueueMultiple(items []interface{}, priorities ...int) ([]interface{}, bool) {
+	var response server.EnqueueMultipleResponse
+
+	request := server.EnqueueMultipleRequest{
+		Items:      make([]json.RawMessage, 0, len(items)),
+		Priorities: priorities,
+	}
+	for i, item := range items {
+		raw, err := c.marshal(item)
+		if err != nil {
+			c.error(err)
+			return items[i:], true
+		}
+		request.Items = append(request.Items, raw)
+	}
+	if !c.do(http.MethodPost, server.OperationEnqueueMultiple, nil, request, &response) {
+		return items, true
+	}
+	if !response.Overflow {
+		return nil, false
+	}
+
+	//KIM: items are enqueued in order, so the remaining items are
+	// always the items at the end
+	return items[len(items)-len(response.Remaining):], true
+}
+
+func (c *client) Peek() []interface{} {
+	items := c.items(server.OperationPeek, nil, nil)
+	if items == nil {
+		return []interface{}{}
+	}
+	return items
+}
+
+func (c *client) PeekHead() (interface{}, bool) {
+	items := c.items(server.OperationPeek, url.Values{server.ParameterN: []string{"1"}}, nil)
+	if len(items) == 0 {
+		return nil, true
+	}
+	return items[0], false
+}
+
+func (c *client) PeekFromHead(n int) []interface{} {
+	return c.items(server.OperationPeek, url.Values{server.ParameterN: []string{strconv.Itoa(n)}}, nil)
+}
+
+func (c *client) Length() int {
+	var response server.LengthResponse
+
+	_ = c.do(http.MethodGet, server.OperationLength, nil, nil, &response)
+	return response.Length
+}
+
+func (c *client) Capacity() int {
+	var response server.CapacityResponse
+
+	_ = c.do(http.MethodGet, server.OperationCapacity, nil, nil, &response)
+	return response.Capacity
+}
+
+func (c *client) Resize(newSize int) []interface{} {
+	return c.items(server.OperationResize, nil, server.ResizeRequest{Size: newSize})
+}
+
+func (c *client) wrappers(query url.Values) []*goqueuepriority.Wrapper {
+	var response server.WrappersResponse
+
+	if !c.do(http.MethodGet, server.OperationWrappers, query, nil, &response) {
+		return nil
+	}
+	wrappers := make([]*goqueuepriority.Wrapper, 0, len(response.Wrappers))
+	for _, wrapper := range response.Wrappers {
+		wrappers = append(wrappers, &goqueuepriority.Wrapper{
+			Priority:   wrapper.Priority,
+			Key:        wrapper.Key,
+			EnqueuedAt: wrapper.EnqueuedAt,
+			Attempts:   wrapper.Attempts,
+			Tenant:     wrapper.Tenant,
+			Partition:  wrapper.Partition,
+			Item:       c.unmarshal(wrapper.Item),
+		})
+	}
+	return wrappers
+}
+
+func (c *client) PeekWrappers() []*goqueuepriority.Wrapper {
+	return c.wrappers(nil)
+}
+
+func (c *client) PeekHeadWrapper() (*goqueuepriority.Wrapper, bool) {
+	wrappers := c.wrappers(url.Values{server.ParameterN: []string{"1"}})
+	if len(wrappers) == 0 {
+		return nil, true
+	}
+	return wrappers[0], false
+}
+
+func (c *client) Reprioritize(from, to int) int {
+	var response server.ReprioritizeResponse
+
+	_ = c.do(http.MethodPost, server.OperationReprioritize, nil, server.ReprioritizeRequest{From: from, To: to}, &response)
+	return response.Count
+}
@@ -0,0 +1,273 @@
+package client_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	client "github.com/antonio-alexander/go-queue-priority/client"
+	goqueuepriorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	server "github.com/antonio-alexander/go-queue-priority/server"
+	finite "github.com/antonio-alexander/go-queue/finite"
+
+	goqueuepriorityfinite_tests "github.com/antonio-alexander/go-queue-priority/finite/tests"
+	finite_tests "github.com/antonio-alexander/go-queue/finite/tests"
+	goqueue_tests "github.com/antonio-alexander/go-queue/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	mustTimeout time.Duration = time.Second
+	mustRate    time.Duration = time.Millisecond
+)
+
+type queue interface {
+	goqueue.Dequeuer
+	goqueue.Enqueuer
+	goqueue.Peeker
+	goqueue.Length
+	goqueuepriority.PriorityEnqueuer
+	goqueuepriority.WrapperPeeker
+	goqueuepriority.Reprioritizer
+	finite.Capacity
+	finite.Resizer
+}
+
+// decodeExample will decode items received from the server as examples
+func decodeExample(raw json.RawMessage) (interface{}, error) {
+	var example goqueue.Example
+
+	err := json.Unmarshal(raw, &example)
+	return example, err
+}
+
+// decodeExamplePointer will decode items received from the server as
+// pointers to examples
+func decodeExamplePointer(raw json.RawMessage) (interface{}, error) {
+	example := &goqueue.Example{}
+
+	err := json.Unmarshal(raw, example)
+	return example, err
+}
+
+// remoteQueue is a client whose close will stop the server
+// and close the queue it serves
+type remoteQueue struct {
+	queue
+	close func() []interface{}
+}
+
+func (r *remoteQueue) Close() []interface{} {
+	return r.close()
+}
+
+func newClient(t *testing.T, size int, parameters ...interface{}) *remoteQueue {
+	q := goqueuepriorityfinite.New(size)
+	httpServer := httptest.NewServer(server.New(map[string]server.Queue{"queue": q}))
+	return &remoteQueue{
+		client.New(httpServer.URL, "queue", append([]interface{}{decodeExample}, parameters...)...),
+		func() []interface{} {
+			httpServer.Close()
+			return q.Close()
+		},
+	}
+}
+
+func TestClientFiniteQueue(t *testing.T) {
+	t.Run("Test Enqueue Multiple", finite_tests.TestEnqueueMultiple(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+	} {
+		return newClient(t, size)
+	}))
+	t.Run("Test Capacity", finite_tests.TestCapacity(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		finite.Capacity
+	} {
+		return newClient(t, size)
+	}))
+}
+
+func TestClientQueue(t *testing.T) {
+	t.Run("Test Dequeue", goqueue_tests.TestDequeue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return newClient(t, size, decodeExamplePointer)
+	}))
+	t.Run("Test Dequeue Multiple", goqueue_tests.TestDequeueMultiple(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return newClient(t, size, decodeExamplePointer)
+	}))
+	t.Run("Test Flush", goqueue_tests.TestFlush(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return newClient(t, size, decodeExamplePointer)
+	}))
+	t.Run("Test Peek", goqueue_tests.TestPeek(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Peeker
+	} {
+		return newClient(t, size, decodeExamplePointer)
+	}))
+	t.Run("Test Peek From Head", goqueue_tests.TestPeekFromHead(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Peeker
+	} {
+		return newClient(t, size, decodeExamplePointer)
+	}))
+	t.Run("Test Length", goqueue_tests.TestLength(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Length
+	} {
+		return newClient(t, size, decodeExamplePointer)
+	}))
+}
+
+func TestClientPriorityQueue(t *testing.T) {
+	t.Run("Test Priority Enqueue", goqueuepriorityfinite_tests.TestPriorityEnqueue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueuepriority.PriorityEnqueuer
+	} {
+		return newClient(t, size)
+	}))
+}
+
+func TestClient(t *testing.T) {
+	var errs []error
+
+	q := newClient(t, 2, client.Configuration{
+		Wait:    time.Second,
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+
+	//validate that a dequeue will wait for an item to be enqueued
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		q.PriorityEnqueue(goqueue.Example{Int: 1}, 1)
+	}()
+	item, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, goqueue.Example{Int: 1}, item)
+
+	//validate that items that are partially enqueued are returned
+	remaining, overflow := q.PriorityEnqueueMultiple([]interface{}{
+		goqueue.Example{Int: 2}, goqueue.Example{Int: 3}, goqueue.Example{Int: 4},
+	}, 1)
+	assert.True(t, overflow)
+	assert.Equal(t, []interface{}{goqueue.Example{Int: 4}}, remaining)
+	assert.Equal(t, 2, q.Reprioritize(1, 2))
+	wrapper, underflow := q.PeekHeadWrapper()
+	assert.False(t, underflow)
+	assert.Equal(t, 2, wrapper.Priority)
+	assert.Equal(t, goqueue.Example{Int: 2}, wrapper.Item)
+	assert.Len(t, q.PeekWrappers(), 2)
+	assert.Equal(t, []interface{}{goqueue.Example{Int: 2}}, q.Resize(1))
+	assert.Equal(t, 1, q.Capacity())
+	assert.Empty(t, errs)
+
+	//validate that errors are reported and treated as an
+	// overflow or underflow
+	q.Close()
+	assert.True(t, q.PriorityEnqueue(goqueue.Example{Int: 5}, 1))
+	_, underflow = q.Dequeue()
+	assert.True(t, underflow)
+	assert.Len(t, errs, 2)
+
+	//validate that unexpected statuses are reported
+	errs = nil
+	httpServer := httptest.NewServer(server.New(nil))
+	defer httpServer.Close()
+	missing := client.New(httpServer.URL, "missing", client.Configuration{
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+	assert.True(t, missing.PriorityEnqueue(goqueue.Example{Int: 6}, 1))
+	assert.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], client.ErrUnexpectedStatus))
+}
+
+// TestClientPriorityFunc is meant to confirm that the priority of items
+// enqueued using Enqueue is determined by the priority function, while
+// items enqueued using PriorityEnqueue without a priority have the default
+// priority
+func TestClientPriorityFunc(t *testing.T) {
+	q := newClient(t, 5, goqueuepriority.PriorityFunc(func(item interface{}) int {
+		return item.(goqueue.Example).Int
+	}))
+	defer q.Close()
+
+	assert.False(t, q.Enqueue(goqueue.Example{Int: 1}))
+	assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 2}))
+	remaining, overflow := q.EnqueueMultiple([]interface{}{
+		goqueue.Example{Int: 3}, goqueue.Example{Int: 4},
+	})
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 5}, 0))
+	var priorities []int
+	for _, wrapper := range q.PeekWrappers() {
+		priorities = append(priorities, wrapper.Priority)
+	}
+	assert.Equal(t, []int{4, 3, 1, 0, 0}, priorities)
+}
+
+// TestClientInterop is meant to confirm that items enqueued using the REST API
+// can be dequeued by the client and vice versa, and that the items within the
+// served queue are plain JSON
+func TestClientInterop(t *testing.T) {
+	var dequeued server.DequeueResponse
+
+	q := goqueuepriorityfinite.New(5)
+	defer q.Close()
+	httpServer := httptest.NewServer(server.New(map[string]server.Queue{"queue": q}))
+	defer httpServer.Close()
+	c := client.New(httpServer.URL, "queue")
+	uri := httpServer.URL + server.PathQueues + "queue/"
+
+	//enqueue using the REST API and dequeue using the client
+	response, err := http.Post(uri+server.OperationEnqueue, "application/json",
+		strings.NewReader(`{"item":{"name":"x"},"priority":1}`))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response.Body.Close()
+	item, underflow := c.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, map[string]interface{}{"name": "x"}, item)
+
+	//enqueue using the client, validate the item within the queue and
+	// dequeue using the REST API
+	assert.False(t, c.PriorityEnqueue(map[string]string{"name": "y"}, 1))
+	items := q.Peek()
+	if assert.Len(t, items, 1) {
+		assert.JSONEq(t, `{"name":"y"}`, string(items[0].(json.RawMessage)))
+	}
+	response, err = http.Post(uri+server.OperationDequeue, "application/json", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&dequeued))
+	response.Body.Close()
+	assert.False(t, dequeued.Underflow)
+	assert.JSONEq(t, `{"name":"y"}`, string(dequeued.Item))
+}
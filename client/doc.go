@@ -0,0 +1,10 @@
+// Copyright 2023 antonio-alexander. All rights reserved.
+// Use of this source code is governed by an MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package client provides a client for a priority queue exposed by the server
+package, the client implements the same interfaces as a local queue so it can
+be used in place of one
+*/
+package client
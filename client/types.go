@@ -0,0 +1,35 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DefaultTimeout is the amount of time a request can take (in addition to
+// the configured wait) if no timeout is configured
+const DefaultTimeout time.Duration = 10 * time.Second
+
+// ErrUnexpectedStatus is provided to the error callback when the server
+// responds with a status other than 200
+var ErrUnexpectedStatus = errors.New("unexpected status")
+
+// Configuration can be provided to New to configure the client
+type Configuration struct {
+	//Timeout limits how long a request can take, excluding the wait
+	Timeout time.Duration
+
+	//Wait is how long the server will wait for an item when dequeuing
+	// if the queue is empty
+	Wait time.Duration
+
+	//OnError is executed whenever a request fails, since the queue
+	// interfaces don't return errors, a failed enqueue is reported as
+	// an overflow and a failed dequeue as an underflow
+	OnError func(err error)
+}
+
+// Decoder can be provided to New to decode items received from the server
+// (e.g. into a concrete type), by default items are decoded into an empty
+// interface
+type Decoder func(raw json.RawMessage) (item interface{}, err error)
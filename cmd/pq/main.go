@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	client "github.com/antonio-alexander/go-queue-priority/client"
+	durable "github.com/antonio-alexander/go-queue-priority/durable"
+	finite "github.com/antonio-alexander/go-queue-priority/finite"
+)
+
+const usage string = `usage: pq [flags] <command> [command flags]
+
+pq can be used to inspect and manipulate a queue served by pqserver (-server),
+a durable queue's log (-wal) or a snapshot (-snapshot); list and stats only
+read the log, so they can be used while pqserver has it open, the other
+commands require exclusive access to the log
+
+commands:
+  list          list items with their priority and age (-n limits the items)
+  stats         print the number of items and the oldest item per priority
+  enqueue       enqueue items from stdin, one JSON object per line with
+                a priority and an item (e.g. {"priority":1,"item":"a"})
+  dequeue       dequeue items and print them as JSON (-n items)
+  reprioritize  change the priority of items (-from priority -to priority)
+
+flags:
+`
+
+// queue describes the functionality required by the commands
+type queue interface {
+	goqueue.Dequeuer
+	goqueuepriority.PriorityEnqueuer
+	goqueuepriority.WrapperPeeker
+	goqueuepriority.Reprioritizer
+}
+
+// line describes a line read from stdin when enqueueing
+type line struct {
+	Priority int         `json:"priority"`
+	Item     interface{} `json:"item"`
+}
+
+func main() {
+	var address, name, wal, snapshot string
+	var size int
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&address, "server", "", "address of a queue server (e.g. http://localhost:8080)")
+	flag.StringVar(&name, "queue", "default", "name of the queue on the server")
+	flag.StringVar(&wal, "wal", "", "path to the log of a durable queue")
+	flag.StringVar(&snapshot, "snapshot", "", "path to a snapshot")
+	flag.IntVar(&size, "size", 1024, "size of the queue when opening a log or snapshot")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), flag.Args()[1:], address, name, wal, snapshot, size); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(command string, args []string, address, name, wal, snapshot string, size int) error {
+	var modified bool
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	n := flags.Int("n", 0, "number of items")
+	from := flags.Int("from", 0, "priority of the items to reprioritize")
+	to := flags.Int("to", 0, "new priority of the items")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	switch command {
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	case "list", "stats", "enqueue", "dequeue", "reprioritize":
+	}
+	if address == "" && wal != "" && (command == "list" || command == "stats") {
+		//KIM: the log is read rather than opened so it isn't locked,
+		// truncated or written to
+		wrappers, err := durable.ReadLog(wal)
+		if err != nil {
+			return err
+		}
+		if command == "list" {
+			return list(os.Stdout, wrappers, *n)
+		}
+		return stats(os.Stdout, wrappers)
+	}
+	q, errs, closeQueue, err := open(address, name, wal, snapshot, size)
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "list":
+		err = list(os.Stdout, q.PeekWrappers(), *n)
+	case "stats":
+		err = stats(os.Stdout, q.PeekWrappers())
+	case "enqueue":
+		modified = true
+		err = enqueue(os.Stdin, os.Stdout, q)
+	case "dequeue":
+		if *n <= 0 {
+			*n = 1
+		}
+		modified = true
+		err = printItems(os.Stdout, q.DequeueMultiple(*n))
+	case "reprioritize":
+		modified = true
+		fmt.Fprintf(os.Stdout, "reprioritized %d item(s)\n", q.Reprioritize(*from, *to))
+	}
+	if closeErr := closeQueue(modified); err == nil {
+		err = closeErr
+	}
+	if err == nil && len(*errs) > 0 {
+		err = errors.Join(*errs...)
+	}
+	return err
+}
+
+// open will open the queue, the returned errors will contain any errors that
+// occur communicating with a server and close must be called to persist any
+// modifications
+func open(address, name, wal, snapshot string, size int) (queue, *[]error, func(modified bool) error, error) {
+	var errs []error
+
+	switch {
+	default:
+		return nil, nil, nil, errors.New("one of -server, -wal or -snapshot is required")
+	case address != "":
+		q := client.New(address, name, client.Configuration{
+			OnError: func(err error) { errs = append(errs, err) },
+		})
+		return q, &errs, func(bool) error { return nil }, nil
+	case wal != "":
+		q, err := durable.Open(wal, size, durable.Configuration{CompactInterval: -1})
+		if errors.Is(err, durable.ErrLocked) {
+			return nil, nil, nil, fmt.Errorf("%s is in use (e.g. by pqserver): %w", wal, err)
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return q, &errs, func(bool) error {
+			q.Close()
+			return nil
+		}, nil
+	case snapshot != "":
+		data, err := os.ReadFile(snapshot)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, nil, err
+		}
+		if len(data) > 0 {
+			wrappers, err := goqueuepriority.ReadSnapshot(bytes.NewReader(data))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if len(wrappers) > size {
+				size = len(wrappers)
+			}
+		}
+		q := finite.New(size)
+		if len(data) > 0 {
+			if err := q.Restore(bytes.NewReader(data)); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		return q, &errs, func(modified bool) error {
+			defer q.Close()
+
+			if !modified {
+				return nil
+			}
+			return writeSnapshot(snapshot, q)
+		}, nil
+	}
+}
+
+// writeSnapshot will write the snapshot to a temporary file and then
+// replace the snapshot at path
+func writeSnapshot(path string, q goqueuepriority.Snapshotter) error {
+	buffer := &bytes.Buffer{}
+	if err := q.Snapshot(buffer); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", buffer.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func marshal(item interface{}) string {
+	bytes, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Sprintf("%v", item)
+	}
+	return string(bytes)
+}
+
+func age(enqueuedAt int64) time.Duration {
+	return time.Since(time.Unix(0, enqueuedAt)).Round(time.Millisecond)
+}
+
+func list(w io.Writer, wrappers []*goqueuepriority.Wrapper, n int) error {
+	if n > 0 && n < len(wrappers) {
+		wrappers = wrappers[:n]
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PRIORITY\tAGE\tATTEMPTS\tTENANT\tITEM")
+	for _, wrapper := range wrappers {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", wrapper.Priority, age(wrapper.EnqueuedAt),
+			wrapper.Attempts, wrapper.Tenant, marshal(wrapper.Item))
+	}
+	return tw.Flush()
+}
+
+func stats(w io.Writer, wrappers []*goqueuepriority.Wrapper) error {
+	counts := make(map[int]int)
+	oldest := make(map[int]int64)
+	for _, wrapper := range wrappers {
+		counts[wrapper.Priority]++
+		if at, ok := oldest[wrapper.Priority]; !ok || wrapper.EnqueuedAt < at {
+			oldest[wrapper.Priority] = wrapper.EnqueuedAt
+		}
+	}
+	priorities := make([]int, 0, len(counts))
+	for priority := range counts {
+		priorities = append(priorities, priority)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PRIORITY\tCOUNT\tOLDEST")
+	for _, priority := range priorities {
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", priority, counts[priority], age(oldest[priority]))
+	}
+	fmt.Fprintf(tw, "total\t%d\t\n", len(wrappers))
+	return tw.Flush()
+}
+
+func enqueue(r io.Reader, w io.Writer, q queue) error {
+	var enqueued int
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for i := 1; scanner.Scan(); i++ {
+		var l line
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			return fmt.Errorf("line %d: %w", i, err)
+		}
+		if overflow := q.PriorityEnqueue(l.Item, l.Priority); overflow {
+			fmt.Fprintf(w, "enqueued %d item(s)\n", enqueued)
+			return fmt.Errorf("line %d: queue is full", i)
+		}
+		enqueued++
+	}
+	fmt.Fprintf(w, "enqueued %d item(s)\n", enqueued)
+	return scanner.Err()
+}
+
+func printItems(w io.Writer, items []interface{}) error {
+	for _, item := range items {
+		if _, err := fmt.Fprintln(w, marshal(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	durable "github.com/antonio-alexander/go-queue-priority/durable"
+	finite "github.com/antonio-alexander/go-queue-priority/finite"
+	server "github.com/antonio-alexander/go-queue-priority/server"
+)
+
+func main() {
+	var address, names, dir string
+	var size int
+	var maxWait time.Duration
+
+	flag.StringVar(&address, "addr", ":8080", "address to listen on")
+	flag.StringVar(&names, "queues", "default", "comma separated names of the queues to serve")
+	flag.IntVar(&size, "size", 1024, "size of each queue")
+	flag.StringVar(&dir, "dir", "", "directory to store durable queue logs, if empty queues are in-memory")
+	flag.DurationVar(&maxWait, "max-wait", server.DefaultMaxWait, "maximum time a dequeue can wait for an item")
+	flag.Parse()
+
+	queues := make(map[string]server.Queue)
+	owners := make([]goqueue.Owner, 0)
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if dir == "" {
+			queue := finite.New(size)
+			queues[name], owners = queue, append(owners, queue)
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Fatal(err)
+		}
+		queue, err := durable.Open(filepath.Join(dir, name+".log"), size)
+		if err != nil {
+			log.Fatal(err)
+		}
+		queues[name], owners = queue, append(owners, queue)
+	}
+	defer func() {
+		for _, owner := range owners {
+			owner.Close()
+		}
+	}()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	httpServer := &http.Server{
+		Addr:    address,
+		Handler: server.New(queues, server.Configuration{MaxWait: maxWait}),
+	}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), maxWait+time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(ctx)
+	}()
+	log.Printf("serving %d queue(s) on %s", len(queues), address)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Print(err)
+		return
+	}
+	<-stopped
+}
@@ -0,0 +1,207 @@
+package priority
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// envelopeVersion is the version of the binary envelope written
+// by MarshalWrapper
+const envelopeVersion byte = 1
+
+// ErrEnvelope is returned when a binary envelope can't be decoded
+var ErrEnvelope = errors.New("invalid wrapper envelope")
+
+// Codec can be used to encode and decode items for storage or transport,
+// implementations should be safe for concurrent use
+type Codec interface {
+	Marshal(item interface{}) (bytes []byte, err error)
+	Unmarshal(bytes []byte) (item interface{}, err error)
+}
+
+// JSONCodec encodes items as JSON along with the name their type was
+// registered with (see RegisterType), items whose type wasn't registered
+// will be decoded into an empty interface
+type JSONCodec struct{}
+
+type jsonItem struct {
+	Type  string          `json:"type,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (JSONCodec) Marshal(item interface{}) ([]byte, error) {
+	name, value, err := marshalItem(item)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonItem{Type: name, Value: value})
+}
+
+func (JSONCodec) Unmarshal(bytes []byte) (interface{}, error) {
+	var j jsonItem
+
+	if err := json.Unmarshal(bytes, &j); err != nil {
+		return nil, err
+	}
+	return unmarshalItem(j.Type, j.Value)
+}
+
+// GobCodec encodes items using encoding/gob, like any interface encoded with
+// gob, the concrete types of items must be registered with gob.Register
+type GobCodec struct{}
+
+func (GobCodec) Marshal(item interface{}) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	if err := gob.NewEncoder(buffer).Encode(&item); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte) (interface{}, error) {
+	var item interface{}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// MarshalWrapper can be used to encode a wrapper into a compact binary envelope,
+// the item is encoded using the codec and each variable length field is prefixed
+// with its length
+func MarshalWrapper(codec Codec, wrapper *Wrapper) ([]byte, error) {
+	item, err := codec.Marshal(wrapper.Item)
+	if err != nil {
+		return nil, err
+	}
+	bytes := make([]byte, 0, 1+(5+len(wrapper.Key))*binary.MaxVarintLen64+len(wrapper.Tenant)+len(wrapper.Partition)+len(item))
+	bytes = append(bytes, envelopeVersion)
+	bytes = binary.AppendVarint(bytes, int64(wrapper.Priority))
+	bytes = binary.AppendUvarint(bytes, uint64(len(wrapper.Key)))
+	for _, level := range wrapper.Key {
+		bytes = binary.AppendVarint(bytes, int64(level))
+	}
+	bytes = binary.AppendVarint(bytes, wrapper.EnqueuedAt)
+	bytes = binary.AppendUvarint(bytes, uint64(wrapper.Attempts))
+	bytes = appendBytes(bytes, []byte(wrapper.Tenant))
+	bytes = appendBytes(bytes, []byte(wrapper.Partition))
+	bytes = appendBytes(bytes, item)
+	return bytes, nil
+}
+
+// UnmarshalWrapper can be used to decode a binary envelope created by
+// MarshalWrapper, the item is decoded using the codec
+func UnmarshalWrapper(codec Codec, data []byte) (*Wrapper, error) {
+	var key []int
+
+	if len(data) == 0 || data[0] != envelopeVersion {
+		return nil, ErrEnvelope
+	}
+	reader := bytes.NewReader(data[1:])
+	priority, err := binary.ReadVarint(reader)
+	if err != nil {
+		return nil, ErrEnvelope
+	}
+	n, err := binary.ReadUvarint(reader)
+	if err != nil || n > uint64(reader.Len()) {
+		return nil, ErrEnvelope
+	}
+	for i := uint64(0); i < n; i++ {
+		level, err := binary.ReadVarint(reader)
+		if err != nil {
+			return nil, ErrEnvelope
+		}
+		key = append(key, int(level))
+	}
+	enqueuedAt, err := binary.ReadVarint(reader)
+	if err != nil {
+		return nil, ErrEnvelope
+	}
+	attempts, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, ErrEnvelope
+	}
+	tenant, err := readBytes(reader)
+	if err != nil {
+		return nil, err
+	}
+	partition, err := readBytes(reader)
+	if err != nil {
+		return nil, err
+	}
+	itemBytes, err := readBytes(reader)
+	if err != nil {
+		return nil, err
+	}
+	if reader.Len() != 0 {
+		return nil, ErrEnvelope
+	}
+	item, err := codec.Unmarshal(itemBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &Wrapper{
+		Priority:   int(priority),
+		Key:        key,
+		EnqueuedAt: enqueuedAt,
+		Attempts:   int(attempts),
+		Tenant:     string(tenant),
+		Partition:  string(partition),
+		Item:       item,
+	}, nil
+}
+
+// WriteWrapper can be used to write a wrapper to a stream, the binary envelope
+// is prefixed with its length (a big endian uint32)
+func WriteWrapper(w io.Writer, codec Codec, wrapper *Wrapper) error {
+	envelope, err := MarshalWrapper(codec, wrapper)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 4, 4+len(envelope))
+	binary.BigEndian.PutUint32(frame, uint32(len(envelope)))
+	_, err = w.Write(append(frame, envelope...))
+	return err
+}
+
+// ReadWrapper can be used to read a wrapper written to a stream by WriteWrapper,
+// io.EOF will be returned if there are no more wrappers and io.ErrUnexpectedEOF
+// will be returned if the wrapper is incomplete
+func ReadWrapper(r io.Reader, codec Codec) (*Wrapper, error) {
+	prefix := make([]byte, 4)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	envelope := &bytes.Buffer{}
+	if _, err := io.CopyN(envelope, r, int64(binary.BigEndian.Uint32(prefix))); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return UnmarshalWrapper(codec, envelope.Bytes())
+}
+
+// appendBytes will append the bytes prefixed by their length
+func appendBytes(dst, src []byte) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	return append(dst, src...)
+}
+
+// readBytes will read bytes prefixed by their length
+func readBytes(reader *bytes.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil || length > uint64(reader.Len()) {
+		return nil, ErrEnvelope
+	}
+	bytes := make([]byte, length)
+	if _, err := io.ReadFull(reader, bytes); err != nil {
+		return nil, ErrEnvelope
+	}
+	return bytes, nil
+}
@@ -0,0 +1,229 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	priorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	finite "github.com/antonio-alexander/go-queue/finite"
+)
+
+var queues = struct {
+	sync.RWMutex
+	byName map[string]Queue
+}{
+	byName: make(map[string]Queue),
+}
+
+// Register can be used to register a queue with the given name, registering
+// a queue with a name that's already registered will replace it
+func Register(name string, queue Queue) {
+	queues.Lock()
+	defer queues.Unlock()
+
+	queues.byName[name] = queue
+}
+
+// Unregister can be used to unregister the queue with the given name
+func Unregister(name string) {
+	queues.Lock()
+	defer queues.Unlock()
+
+	delete(queues.byName, name)
+}
+
+// Handler can be used to create a handler to introspect registered queues, an
+// ItemFormatter can optionally be provided as a parameter to format items; the
+// handler isn't registered with any mux, so queues are only exposed where it's
+// mounted (e.g. mux.Handle(Path, debug.Handler()))
+func Handler(parameters ...interface{}) http.Handler {
+	formatter := ItemFormatter(func(item interface{}) string {
+		return fmt.Sprintf("%T", item)
+	})
+	for _, parameter := range parameters {
+		switch p := parameter.(type) {
+		case ItemFormatter:
+			formatter = p
+		case func(item interface{}) string:
+			formatter = p
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		now := time.Now()
+		name := r.URL.Query().Get(ParameterQueue)
+		if name == "" {
+			writeJSON(w, index(now))
+			return
+		}
+		queues.RLock()
+		queue, ok := queues.byName[name]
+		queues.RUnlock()
+		if !ok {
+			http.Error(w, fmt.Sprintf("queue %q not found", name), http.StatusNotFound)
+			return
+		}
+		offset, err := parseInt(r, ParameterOffset, 0)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit, err := parseInt(r, ParameterLimit, DefaultLimit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+		writeJSON(w, page(now, name, queue, offset, limit, formatter))
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+func parseInt(r *http.Request, parameter string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(parameter)
+	if value == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", parameter, value)
+	}
+	return i, nil
+}
+
+func age(now time.Time, enqueuedAt int64) time.Duration {
+	return now.Sub(time.Unix(0, enqueuedAt))
+}
+
+// summarize will summarize the queue using its statistics if it implements
+// priorityfinite.Statistics, otherwise using all of its wrappers
+func summarize(now time.Time, name string, queue Queue) Summary {
+	var counts map[int]int
+	var oldest map[int]int64
+
+	summary := Summary{
+		Name:       name,
+		Length:     queue.Length(),
+		Priorities: []Priority{},
+	}
+	if capacity, ok := queue.(finite.Capacity); ok {
+		c := capacity.Capacity()
+		summary.Capacity = &c
+	}
+	if statistics, ok := queue.(priorityfinite.Statistics); ok {
+		counts = statistics.LengthByPriority()
+		oldest = make(map[int]int64)
+		for priority, enqueuedAt := range statistics.OldestByPriority() {
+			oldest[priority] = enqueuedAt.UnixNano()
+		}
+	} else {
+		counts, oldest = make(map[int]int), make(map[int]int64)
+		for _, wrapper := range queue.PeekWrappers() {
+			counts[wrapper.Priority]++
+			if enqueuedAt, ok := oldest[wrapper.Priority]; !ok || wrapper.EnqueuedAt < enqueuedAt {
+				oldest[wrapper.Priority] = wrapper.EnqueuedAt
+			}
+		}
+	}
+	for priority, count := range counts {
+		p := Priority{Priority: priority, Count: count}
+		if enqueuedAt, ok := oldest[priority]; ok {
+			p.OldestAge = age(now, enqueuedAt).String()
+		}
+		summary.Priorities = append(summary.Priorities, p)
+	}
+	if len(oldest) > 0 {
+		var oldestAt int64
+
+		first := true
+		for _, enqueuedAt := range oldest {
+			if first || enqueuedAt < oldestAt {
+				oldestAt, first = enqueuedAt, false
+			}
+		}
+		summary.OldestAge = age(now, oldestAt).String()
+	}
+	sort.Slice(summary.Priorities, func(i, j int) bool {
+		return summary.Priorities[i].Priority > summary.Priorities[j].Priority
+	})
+	return summary
+}
+
+// peek will return up to n wrappers from the front of the queue, only
+// queues that don't implement goqueuepriority.WrapperHeadPeeker will
+// have every wrapper copied
+func peek(queue Queue, n int) []*goqueuepriority.Wrapper {
+	if peeker, ok := queue.(goqueuepriority.WrapperHeadPeeker); ok {
+		return peeker.PeekWrappersFromHead(n)
+	}
+	wrappers := queue.PeekWrappers()
+	if n < len(wrappers) {
+		wrappers = wrappers[:n]
+	}
+	return wrappers
+}
+
+func index(now time.Time) Index {
+	queues.RLock()
+	names := make([]string, 0, len(queues.byName))
+	byName := make(map[string]Queue, len(queues.byName))
+	for name, queue := range queues.byName {
+		names = append(names, name)
+		byName[name] = queue
+	}
+	queues.RUnlock()
+
+	sort.Strings(names)
+	index := Index{Queues: make([]Summary, 0, len(names))}
+	for _, name := range names {
+		index.Queues = append(index.Queues, summarize(now, name, byName[name]))
+	}
+	return index
+}
+
+func page(now time.Time, name string, queue Queue, offset, limit int, formatter ItemFormatter) Page {
+	wrappers := peek(queue, offset+limit)
+	page := Page{
+		Summary:  summarize(now, name, queue),
+		Offset:   offset,
+		Limit:    limit,
+		Wrappers: []Wrapper{},
+	}
+	if offset >= len(wrappers) {
+		return page
+	}
+	wrappers = wrappers[offset:]
+	if limit < len(wrappers) {
+		wrappers = wrappers[:limit]
+	}
+	for _, wrapper := range wrappers {
+		page.Wrappers = append(page.Wrappers, Wrapper{
+			Priority:   wrapper.Priority,
+			Key:        wrapper.Key,
+			EnqueuedAt: time.Unix(0, wrapper.EnqueuedAt).UTC().Format(time.RFC3339Nano),
+			Age:        age(now, wrapper.EnqueuedAt).String(),
+			Attempts:   wrapper.Attempts,
+			Tenant:     wrapper.Tenant,
+			Partition:  wrapper.Partition,
+			Item:       formatter(wrapper.Item),
+		})
+	}
+	return page
+}
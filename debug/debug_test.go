@@ -0,0 +1,140 @@
+package debug_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	debug "github.com/antonio-alexander/go-queue-priority/debug"
+	goqueuepriorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, handler http.Handler, url string, response interface{}) int {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+	if response != nil && recorder.Code == http.StatusOK {
+		assert.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+	}
+	return recorder.Code
+}
+
+func TestDebug(t *testing.T) {
+	q := goqueuepriorityfinite.New(5)
+	defer q.Close()
+	assert.False(t, q.PriorityEnqueue("secret1", 1))
+	assert.False(t, q.PriorityEnqueue("secret2", 2))
+	assert.False(t, q.PriorityEnqueue("secret3", 1))
+	debug.Register("jobs", q)
+	defer debug.Unregister("jobs")
+
+	//validate the summary of each queue
+	var index debug.Index
+	handler := debug.Handler()
+	assert.Equal(t, http.StatusOK, get(t, handler, "/debug/queues", &index))
+	if assert.Len(t, index.Queues, 1) {
+		summary := index.Queues[0]
+		assert.Equal(t, "jobs", summary.Name)
+		assert.Equal(t, 3, summary.Length)
+		if assert.NotNil(t, summary.Capacity) {
+			assert.Equal(t, 5, *summary.Capacity)
+		}
+		assert.NotEmpty(t, summary.OldestAge)
+		if assert.Len(t, summary.Priorities, 2) {
+			assert.Equal(t, 2, summary.Priorities[0].Priority)
+			assert.Equal(t, 1, summary.Priorities[0].Count)
+			assert.Equal(t, 1, summary.Priorities[1].Priority)
+			assert.Equal(t, 2, summary.Priorities[1].Count)
+		}
+	}
+
+	//validate that wrappers are paginated and that items aren't
+	// leaked unless a formatter is provided
+	var page debug.Page
+	assert.Equal(t, http.StatusOK, get(t, handler, "/debug/queues?queue=jobs&offset=1&limit=1", &page))
+	assert.Equal(t, 3, page.Length)
+	if assert.Len(t, page.Wrappers, 1) {
+		assert.Equal(t, 1, page.Wrappers[0].Priority)
+		assert.Equal(t, "string", page.Wrappers[0].Item)
+	}
+	handler = debug.Handler(debug.ItemFormatter(func(item interface{}) string {
+		return fmt.Sprintf("%v", item)
+	}))
+	page = debug.Page{}
+	assert.Equal(t, http.StatusOK, get(t, handler, "/debug/queues?queue=jobs", &page))
+	if assert.Len(t, page.Wrappers, 3) {
+		assert.Equal(t, "secret2", page.Wrappers[0].Item)
+	}
+	page = debug.Page{}
+	assert.Equal(t, http.StatusOK, get(t, handler, "/debug/queues?queue=jobs&offset=5", &page))
+	assert.Empty(t, page.Wrappers)
+
+	//validate errors
+	assert.Equal(t, http.StatusNotFound, get(t, handler, "/debug/queues?queue=missing", nil))
+	assert.Equal(t, http.StatusBadRequest, get(t, handler, "/debug/queues?queue=jobs&limit=-1", nil))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/debug/queues", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	//validate that the handler isn't registered with the default mux and
+	// that unregistered queues are removed
+	assert.Equal(t, http.StatusNotFound, get(t, http.DefaultServeMux, debug.Path, nil))
+	debug.Unregister("jobs")
+	index = debug.Index{}
+	assert.Equal(t, http.StatusOK, get(t, handler, debug.Path, &index))
+	assert.Empty(t, index.Queues)
+}
+
+type statisticsQueue interface {
+	debug.Queue
+	goqueuepriority.WrapperHeadPeeker
+	goqueuepriorityfinite.Statistics
+}
+
+// boundedQueue is a queue that records if every wrapper was peeked
+type boundedQueue struct {
+	statisticsQueue
+	peekedAll bool
+}
+
+func (b *boundedQueue) PeekWrappers() []*goqueuepriority.Wrapper {
+	b.peekedAll = true
+	return b.statisticsQueue.PeekWrappers()
+}
+
+// TestDebugBounded is meant to confirm that queues that can peek wrappers
+// from the head and provide statistics don't have every wrapper copied
+func TestDebugBounded(t *testing.T) {
+	var index debug.Index
+	var page debug.Page
+
+	q := goqueuepriorityfinite.New(10)
+	defer q.Close()
+	for i := 0; i < 10; i++ {
+		assert.False(t, q.PriorityEnqueue(i, i%2))
+	}
+	bounded := &boundedQueue{q, false}
+	debug.Register("bounded", bounded)
+	defer debug.Unregister("bounded")
+
+	handler := debug.Handler()
+	assert.Equal(t, http.StatusOK, get(t, handler, debug.Path, &index))
+	if assert.Len(t, index.Queues, 1) {
+		assert.NotEmpty(t, index.Queues[0].OldestAge)
+		if assert.Len(t, index.Queues[0].Priorities, 2) {
+			assert.Equal(t, 5, index.Queues[0].Priorities[0].Count)
+			assert.NotEmpty(t, index.Queues[0].Priorities[0].OldestAge)
+		}
+	}
+	assert.Equal(t, http.StatusOK, get(t, handler, debug.Path+"?queue=bounded&offset=4&limit=2", &page))
+	assert.Equal(t, 10, page.Length)
+	if assert.Len(t, page.Wrappers, 2) {
+		assert.Equal(t, 1, page.Wrappers[0].Priority)
+		assert.Equal(t, 0, page.Wrappers[1].Priority)
+	}
+	assert.False(t, bounded.peekedAll)
+}
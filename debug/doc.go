@@ -0,0 +1,18 @@
+// Copyright 2023 antonio-alexander. All rights reserved.
+// Use of this source code is governed by an MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package debug provides a read-only http handler to introspect the priority
+queues registered with it. The handler isn't registered with any mux, it must
+be mounted explicitly (e.g. at Path) so queues aren't exposed without opting in:
+
+	mux.Handle(debug.Path, debug.Handler())
+
+The index lists every registered queue with its length, capacity, the number
+of items per priority and the age of its oldest item; the wrappers of a queue
+can be viewed a page at a time with /debug/queues?queue=name&offset=0&limit=100.
+Items are formatted using only their type unless an ItemFormatter is provided
+to Handler so payloads aren't leaked
+*/
+package debug
@@ -0,0 +1,76 @@
+package debug
+
+import (
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+)
+
+// Path is the conventional path to mount the handler at
+const Path string = "/debug/queues"
+
+// DefaultLimit is the number of wrappers shown per page if no
+// limit is provided
+const DefaultLimit int = 100
+
+// MaxLimit is the maximum number of wrappers shown per page
+const MaxLimit int = 1000
+
+// These are the query parameters that can be provided to the handler
+const (
+	ParameterQueue  string = "queue"
+	ParameterOffset string = "offset"
+	ParameterLimit  string = "limit"
+)
+
+// Queue describes the functionality a queue must provide to be registered,
+// its capacity is included if it also implements finite.Capacity
+type Queue interface {
+	goqueue.Length
+	goqueuepriority.WrapperPeeker
+}
+
+// ItemFormatter can be provided to Handler to format the items of each
+// wrapper, by default only the type of the item is shown
+type ItemFormatter func(item interface{}) string
+
+// Priority summarizes the items with the same priority
+type Priority struct {
+	Priority  int    `json:"priority"`
+	Count     int    `json:"count"`
+	OldestAge string `json:"oldest_age"`
+}
+
+// Summary summarizes a queue, capacity is omitted if the queue
+// doesn't have a capacity
+type Summary struct {
+	Name       string     `json:"name"`
+	Length     int        `json:"length"`
+	Capacity   *int       `json:"capacity,omitempty"`
+	OldestAge  string     `json:"oldest_age,omitempty"`
+	Priorities []Priority `json:"priorities"`
+}
+
+// Wrapper describes the metadata of an item in the queue
+type Wrapper struct {
+	Priority   int    `json:"priority"`
+	Key        []int  `json:"key,omitempty"`
+	EnqueuedAt string `json:"enqueued_at"`
+	Age        string `json:"age"`
+	Attempts   int    `json:"attempts,omitempty"`
+	Tenant     string `json:"tenant,omitempty"`
+	Partition  string `json:"partition,omitempty"`
+	Item       string `json:"item"`
+}
+
+// Index is the response of the handler when no queue is provided
+type Index struct {
+	Queues []Summary `json:"queues"`
+}
+
+// Page is the response of the handler when a queue is provided
+type Page struct {
+	Summary
+	Offset   int       `json:"offset"`
+	Limit    int       `json:"limit"`
+	Wrappers []Wrapper `json:"wrappers"`
+}
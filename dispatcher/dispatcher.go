@@ -0,0 +1,169 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+)
+
+type dispatcher struct {
+	sync.WaitGroup
+	queue   Queue
+	handler Handler
+	config  Configuration
+	stopper chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	once    sync.Once
+}
+
+// New can be used to create a dispatcher that will immediately start the
+// given number of workers to consume items from the queue, a Configuration
+// can optionally be provided as a parameter
+func New(queue Queue, workers int, handler Handler, parameters ...interface{}) Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &dispatcher{
+		queue:   queue,
+		handler: handler,
+		stopper: make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for _, parameter := range parameters {
+		switch p := parameter.(type) {
+		case Configuration:
+			d.config = p
+		case *Configuration:
+			d.config = *p
+		}
+	}
+	if d.config.PollInterval <= 0 {
+		d.config.PollInterval = DefaultPollInterval
+	}
+	for i := 0; i < workers; i++ {
+		d.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+func (d *dispatcher) work() {
+	defer d.Done()
+
+	tPoll := time.NewTicker(d.config.PollInterval)
+	defer tPoll.Stop()
+	for {
+		select {
+		case <-d.stopper:
+			return
+		default:
+		}
+		if wrapper, underflow := d.dequeue(); !underflow {
+			d.handle(wrapper)
+			continue
+		}
+		//KIM: the signal channel will be closed (and re-created) if the
+		// queue is resized or closed, in which case we fall back on
+		// the poll interval to avoid spinning
+		select {
+		case <-d.stopper:
+			return
+		case <-tPoll.C:
+		case _, ok := <-d.queue.GetSignalIn():
+			if !ok {
+				select {
+				case <-d.stopper:
+					return
+				case <-tPoll.C:
+				}
+			}
+		}
+	}
+}
+
+// dequeue will dequeue the next item along with its wrapper, if the queue
+// can't dequeue wrappers, the item is wrapped with the requeue priority
+func (d *dispatcher) dequeue() (*goqueuepriority.Wrapper, bool) {
+	if dequeuer, ok := d.queue.(goqueuepriority.WrapperDequeuer); ok {
+		return dequeuer.DequeueWrapper()
+	}
+	item, underflow := d.queue.Dequeue()
+	if underflow {
+		return nil, true
+	}
+	return &goqueuepriority.Wrapper{
+		Item:       item,
+		Priority:   d.config.RequeuePriority,
+		EnqueuedAt: time.Now().UnixNano(),
+	}, false
+}
+
+func (d *dispatcher) handle(wrapper *goqueuepriority.Wrapper) {
+	ctx, cancel := d.ctx, context.CancelFunc(func() {})
+	if d.config.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(d.ctx, d.config.Timeout)
+	}
+	defer cancel()
+	err := d.execute(ctx, wrapper.Item)
+	if err == nil {
+		return
+	}
+	d.error(wrapper.Item, err)
+	if d.config.Retry != nil {
+		d.Add(1)
+		go d.requeue(wrapper)
+	}
+}
+
+func (d *dispatcher) execute(ctx context.Context, item interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+	return d.handler(ctx, item)
+}
+
+func (d *dispatcher) error(item interface{}, err error) {
+	if d.config.OnError != nil {
+		d.config.OnError(item, err)
+	}
+}
+
+func (d *dispatcher) requeue(wrapper *goqueuepriority.Wrapper) {
+	defer d.Done()
+
+	enqueuer, ok := d.queue.(goqueuepriority.WrapperEnqueuer)
+	if !ok {
+		d.error(wrapper.Item, ErrRetryUnsupported)
+		return
+	}
+	//KIM: if the dispatcher is shutdown while waiting to re-enqueue
+	// the item is re-enqueued immediately so it isn't lost
+	if _, overflow := d.config.Retry.Retry(enqueuer, wrapper, d.stopper); overflow {
+		d.error(wrapper.Item, ErrRequeueOverflow)
+	}
+}
+
+func (d *dispatcher) Shutdown(ctx context.Context) error {
+	d.once.Do(func() { close(d.stopper) })
+	done := make(chan struct{})
+	go func() {
+		d.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,174 @@
+package dispatcher_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	dispatcher "github.com/antonio-alexander/go-queue-priority/dispatcher"
+	goqueuepriorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const timeout time.Duration = time.Second
+
+func TestDispatcherPriority(t *testing.T) {
+	var mu sync.Mutex
+	var handled []interface{}
+
+	q := goqueuepriorityfinite.New(10)
+	defer q.Close()
+	for i, priority := range []int{1, 3, 2} {
+		assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: i}, priority))
+	}
+	d := dispatcher.New(q, 1, func(ctx context.Context, item interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, item)
+		return nil
+	})
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(handled) == 3
+	}, timeout, time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	assert.Nil(t, d.Shutdown(ctx))
+	assert.Equal(t, []interface{}{
+		goqueue.Example{Int: 1},
+		goqueue.Example{Int: 2},
+		goqueue.Example{Int: 0},
+	}, handled)
+}
+
+func TestDispatcherErrors(t *testing.T) {
+	errFailed := errors.New("failed")
+	errs := make(chan error, 10)
+	q := goqueuepriorityfinite.New(10)
+	defer q.Close()
+	d := dispatcher.New(q, 2, func(ctx context.Context, item interface{}) error {
+		switch item.(string) {
+		case "panic":
+			panic("oops")
+		case "timeout":
+			<-ctx.Done()
+			return ctx.Err()
+		default:
+			return errFailed
+		}
+	}, &dispatcher.Configuration{
+		Timeout:      10 * time.Millisecond,
+		PollInterval: time.Millisecond,
+		OnError: func(item interface{}, err error) {
+			errs <- err
+		},
+	})
+	for _, item := range []string{"panic", "timeout", "fail"} {
+		assert.False(t, q.Enqueue(item))
+	}
+	var received []error
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-errs:
+			received = append(received, err)
+		case <-time.After(timeout):
+			assert.Fail(t, "timed out waiting for error")
+		}
+	}
+	var panicked, timedOut, failed bool
+	for _, err := range received {
+		panicked = panicked || errors.Is(err, dispatcher.ErrPanic)
+		timedOut = timedOut || errors.Is(err, context.DeadlineExceeded)
+		failed = failed || errors.Is(err, errFailed)
+	}
+	assert.True(t, panicked)
+	assert.True(t, timedOut)
+	assert.True(t, failed)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	assert.Nil(t, d.Shutdown(ctx))
+}
+
+// TestDispatcherRetryUnsupported is meant to confirm that items aren't
+// retried if the queue can't enqueue wrappers
+func TestDispatcherRetryUnsupported(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+
+	q := goqueuepriorityfinite.New(10)
+	defer q.Close()
+	d := dispatcher.New(struct{ dispatcher.Queue }{q}, 1, func(ctx context.Context, item interface{}) error {
+		return errors.New("failed")
+	}, dispatcher.Configuration{
+		PollInterval: time.Millisecond,
+		Retry:        &goqueuepriority.RetryPolicy{},
+		OnError: func(item interface{}, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	assert.False(t, q.Enqueue("item"))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) == 2
+	}, timeout, time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	assert.Nil(t, d.Shutdown(ctx))
+	assert.True(t, errors.Is(errs[1], dispatcher.ErrRetryUnsupported))
+	assert.Equal(t, 0, q.Length())
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	var mu sync.Mutex
+	var handled []interface{}
+
+	deadLetters := make(chan *goqueuepriority.Wrapper, 1)
+	q := goqueuepriorityfinite.New(10)
+	defer q.Close()
+	d := dispatcher.New(q, 1, func(ctx context.Context, item interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, item)
+		return errors.New("failed")
+	}, dispatcher.Configuration{
+		PollInterval: time.Millisecond,
+		Retry: &goqueuepriority.RetryPolicy{
+			MaxAttempts:        2,
+			Backoff:            time.Millisecond,
+			PriorityAdjustment: 1,
+			DeadLetter: func(wrapper *goqueuepriority.Wrapper) {
+				deadLetters <- wrapper
+			},
+		},
+	})
+	assert.False(t, q.PriorityEnqueue("item", 5))
+	select {
+	case wrapper := <-deadLetters:
+		assert.Equal(t, "item", wrapper.Item)
+		assert.Equal(t, 2, wrapper.Attempts)
+		assert.Equal(t, 6, wrapper.Priority)
+	case <-time.After(timeout):
+		assert.Fail(t, "timed out waiting for dead letter")
+	}
+	assert.False(t, q.Enqueue("fail"))
+	select {
+	case wrapper := <-deadLetters:
+		assert.Equal(t, "fail", wrapper.Item)
+	case <-time.After(timeout):
+		assert.Fail(t, "timed out waiting for dead letter")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	assert.Nil(t, d.Shutdown(ctx))
+	assert.Equal(t, 0, q.Length())
+	assert.Equal(t, []interface{}{"item", "item", "fail", "fail"}, handled)
+}
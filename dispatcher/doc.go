@@ -0,0 +1,9 @@
+// Copyright 2023 antonio-alexander. All rights reserved.
+// Use of this source code is governed by an MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package dispatcher provides a worker pool that consumes items from a priority
+queue in priority order and executes a handler for each item
+*/
+package dispatcher
@@ -0,0 +1,71 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+)
+
+// DefaultPollInterval is the default interval at which workers will attempt
+// to dequeue if they haven't received a signal
+const DefaultPollInterval time.Duration = 100 * time.Millisecond
+
+// ErrPanic is provided to the error callback when a handler panics
+var ErrPanic = errors.New("handler panicked")
+
+// ErrRequeueOverflow is provided to the error callback when an item
+// couldn't be re-enqueued because the queue was full
+var ErrRequeueOverflow = errors.New("unable to re-enqueue item, queue is full")
+
+// ErrRetryUnsupported is provided to the error callback when an item
+// can't be retried because the queue can't enqueue wrappers
+var ErrRetryUnsupported = errors.New("unable to retry item, queue can't enqueue wrappers")
+
+// Queue describes the functionality a queue must provide to be
+// consumed by a dispatcher
+type Queue interface {
+	goqueue.Dequeuer
+	goqueue.Event
+	goqueuepriority.PriorityEnqueuer
+}
+
+// Handler describes the function executed for each dequeued item,
+// the context will be cancelled if the handler times out or the
+// dispatcher is forcibly shutdown
+type Handler func(ctx context.Context, item interface{}) error
+
+// Dispatcher describes the functionality of a worker pool
+type Dispatcher interface {
+	//Shutdown will stop all workers from dequeuing, wait for in-flight
+	// handlers to complete and pending re-enqueues to occur; if the
+	// context is done first, the context of in-flight handlers are
+	// cancelled and the context error is returned without waiting
+	Shutdown(ctx context.Context) error
+}
+
+// Configuration can be provided to New to configure the dispatcher
+type Configuration struct {
+	//Timeout, if greater than 0, limits how long each handler can
+	// execute
+	Timeout time.Duration
+
+	//PollInterval is the interval at which workers will attempt to
+	// dequeue when no signal has been received
+	PollInterval time.Duration
+
+	//OnError is executed whenever a handler returns an error or panics
+	OnError func(item interface{}, err error)
+
+	//Retry, if provided, will be used to re-enqueue items whose handler
+	// failed, the queue must implement goqueuepriority.WrapperEnqueuer
+	// (otherwise ErrRetryUnsupported is provided to OnError and the item
+	// isn't re-enqueued); if the queue implements
+	// goqueuepriority.WrapperDequeuer, items are re-enqueued with their
+	// original priority (plus the adjustment), otherwise RequeuePriority
+	// is used as their original priority
+	Retry           *goqueuepriority.RetryPolicy
+	RequeuePriority int
+}
@@ -0,0 +1,10 @@
+// Copyright 2023 antonio-alexander. All rights reserved.
+// Use of this source code is governed by an MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package prioritydurable provides a finite priority queue that persists every
+enqueue and dequeue to an append-only log on disk; when the queue is opened,
+the log is replayed to rebuild the queue with the same priorities and order
+*/
+package prioritydurable
@@ -0,0 +1,853 @@
+package prioritydurable
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	priorityqueue "github.com/antonio-alexander/go-queue-priority"
+	priorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	internal "github.com/antonio-alexander/go-queue-priority/internal"
+	finite "github.com/antonio-alexander/go-queue/finite"
+)
+
+type queueDurable struct {
+	sync.RWMutex
+	sync.WaitGroup
+	path         string
+	file         *os.File
+	config       Configuration
+	size         int
+	sequence     uint64
+	records      int
+	data         []*entry
+	signalIn     chan struct{}
+	signalOut    chan struct{}
+	stopper      chan struct{}
+	observer     priorityqueue.Observer
+	priorityFunc priorityqueue.PriorityFunc
+	codec        priorityqueue.Codec
+}
+
+// Open can be used to open (or create) a durable priority queue with the given size
+// whose log is stored at path; any items within the log will be restored (if there are
+// more items than the size, the size will be increased to fit them). The optional
+// parameters can be used to further configure the queue:
+//   - Configuration: configures how the log is synced and compacted
+//   - priorityqueue.Observer: will be notified as items move through the queue
+//   - priorityqueue.PriorityFunc: determines the priority of items enqueued using
+//     Enqueue, otherwise items that implement priorityqueue.Prioritizer provide their own
+//     priority (items enqueued using PriorityEnqueue without a priority have the
+//     default priority)
+//   - priorityqueue.Codec: used to encode items, by default priorityqueue.JSONCodec
+//     is used so items whose type is registered (see priorityqueue.RegisterType)
+//     retain their type when restored
+//
+// The log is locked while the queue is open, so opening a log that's already open
+// (e.g. by another process) will return ErrLocked; use ReadLog to read the items of a
+// log that's open.
+//
+// Close will return the items remaining in the queue, but they also remain in the log
+// and will be restored when the queue is opened again (flush the queue before closing
+// it to remove them from the log)
+func Open(path string, size int, parameters ...interface{}) (interface {
+	goqueue.Owner
+	goqueue.GarbageCollecter
+	goqueue.Length
+	goqueue.Event
+	goqueue.Peeker
+	goqueue.Dequeuer
+	goqueue.Enqueuer
+	finite.EnqueueLossy
+	finite.Resizer
+	finite.Capacity
+	priorityqueue.PriorityEnqueuer
+	priorityqueue.WrapperPeeker
+	priorityqueue.WrapperHeadPeeker
+	priorityqueue.Snapshotter
+	priorityqueue.Reprioritizer
+	priorityfinite.PriorityEnqueueLossy
+	Compacter
+}, error) {
+	if size < 1 {
+		size = 1
+	}
+	q := &queueDurable{
+		path:    path,
+		size:    size,
+		stopper: make(chan struct{}),
+		codec:   priorityqueue.JSONCodec{},
+	}
+	for _, parameter := range parameters {
+		switch p := parameter.(type) {
+		case Configuration:
+			q.config = p
+		case *Configuration:
+			q.config = *p
+		case priorityqueue.Observer:
+			q.observer = p
+		case priorityqueue.PriorityFunc:
+			q.priorityFunc = p
+		case func(item interface{}) int:
+			q.priorityFunc = p
+		case priorityqueue.Codec:
+			q.codec = p
+		}
+	}
+	if q.config.FsyncInterval <= 0 {
+		q.config.FsyncInterval = DefaultFsyncInterval
+	}
+	if q.config.CompactInterval == 0 {
+		q.config.CompactInterval = DefaultCompactInterval
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := internal.Lock(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	q.file = file
+	if err := q.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if len(q.data) > q.size {
+		q.size = len(q.data)
+	}
+	q.signalIn = make(chan struct{}, q.size)
+	q.signalOut = make(chan struct{}, q.size)
+	q.launch()
+	return q, nil
+}
+
+// ReadLog can be used to read the items within the log at path without
+// opening the queue; the log isn't locked or modified, so it can be read
+// while the queue is open (e.g. to inspect it), an incomplete record at the
+// end of the log is ignored. A priorityqueue.Codec can optionally be
+// provided as a parameter, by default priorityqueue.JSONCodec is used
+func ReadLog(path string, parameters ...interface{}) ([]*priorityqueue.Wrapper, error) {
+	var codec priorityqueue.Codec = priorityqueue.JSONCodec{}
+
+	for _, parameter := range parameters {
+		if p, ok := parameter.(priorityqueue.Codec); ok {
+			codec = p
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, _, _, _, err := read(file, codec)
+	if err != nil {
+		return nil, err
+	}
+	wrappers := make([]*priorityqueue.Wrapper, 0, len(data))
+	for _, e := range data {
+		wrappers = append(wrappers, e.wrapper)
+	}
+	return wrappers, nil
+}
+
+// replay will read the log and rebuild the queue, if the last record of the log
+// is incomplete (e.g. the process stopped while writing it), it's truncated
+func (q *queueDurable) replay() error {
+	data, offset, records, sequence, err := read(q.file, q.codec)
+	if err != nil {
+		return err
+	}
+	if err := q.file.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := q.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	q.data, q.records, q.sequence = data, records, sequence
+	return nil
+}
+
+// read will read the records of the log and return the entries that remain
+// in the queue (sorted) along with the offset of the last complete record,
+// the number of records and the greatest id within the log
+func read(r io.Reader, codec priorityqueue.Codec) ([]*entry, int64, int, uint64, error) {
+	var offset int64
+	var records int
+	var sequence uint64
+
+	entries := make(map[uint64]*entry)
+	reader := bufio.NewReader(r)
+	for {
+		r, n, err := decodeRecord(reader, codec)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if _, err := reader.Peek(1); err == nil {
+				return nil, 0, 0, 0, errors.New("log is corrupt")
+			}
+			break
+		}
+		offset += n
+		records++
+		if r.ID > sequence {
+			sequence = r.ID
+		}
+		switch r.Operation {
+		case operationEnqueue:
+			entries[r.ID] = &entry{id: r.ID, wrapper: r.Wrapper}
+		case operationDequeue:
+			delete(entries, r.ID)
+		}
+	}
+	data := make([]*entry, 0, len(entries))
+	for _, entry := range entries {
+		data = append(data, entry)
+	}
+	sort.Slice(data, func(i, j int) bool { return less(data[i], data[j]) })
+	return data, offset, records, sequence, nil
+}
+
+// launch will start a goroutine to periodically sync and compact
+// the log (if configured)
+func (q *queueDurable) launch() {
+	if q.config.Fsync != FsyncInterval && q.config.CompactInterval < 0 {
+		return
+	}
+	q.Add(1)
+	go func() {
+		defer q.Done()
+
+		var syncC, compactC <-chan time.Time
+
+		if q.config.Fsync == FsyncInterval {
+			tSync := time.NewTicker(q.config.FsyncInterval)
+			defer tSync.Stop()
+			syncC = tSync.C
+		}
+		if q.config.CompactInterval > 0 {
+			tCompact := time.NewTicker(q.config.CompactInterval)
+			defer tCompact.Stop()
+			compactC = tCompact.C
+		}
+		for {
+			select {
+			case <-q.stopper:
+				return
+			case <-syncC:
+				q.Lock()
+				if q.file != nil {
+					if err := q.file.Sync(); err != nil {
+						q.error(err)
+					}
+				}
+				q.Unlock()
+			case <-compactC:
+				if err := q.Compact(); err != nil {
+					q.error(err)
+				}
+			}
+		}
+	}()
+}
+
+// less will determine if entry a should be dequeued before entry b,
+// entries that are equal are ordered by their id
+func less(a, b *entry) bool {
+	if priorityqueue.Less(a.wrapper, b.wrapper) {
+		return true
+	}
+	if priorityqueue.Less(b.wrapper, a.wrapper) {
+		return false
+	}
+	return a.id < b.id
+}
+
+// write will append the records to the log and sync them if configured
+func (q *queueDurable) write(records ...record) error {
+	if q.file == nil {
+		return os.ErrClosed
+	}
+	buffer := &bytes.Buffer{}
+	for _, r := range records {
+		if err := encodeRecord(buffer, q.codec, r); err != nil {
+			return err
+		}
+	}
+	if _, err := q.file.Write(buffer.Bytes()); err != nil {
+		return err
+	}
+	q.records += len(records)
+	if q.config.Fsync == FsyncAlways {
+		return q.file.Sync()
+	}
+	return nil
+}
+
+// error will provide the error to the error handler (if configured)
+func (q *queueDurable) error(err error) {
+	if q.config.OnError != nil {
+		q.config.OnError(err)
+	}
+}
+
+// insert will place the entry into the queue such that it remains sorted
+func (q *queueDurable) insert(e *entry) {
+	index := sort.Search(len(q.data), func(i int) bool { return less(e, q.data[i]) })
+	q.data = append(q.data, nil)
+	copy(q.data[index+1:], q.data[index:])
+	q.data[index] = e
+}
+
+// remove will remove up to n entries from the front of the queue and
+// log that they've been dequeued; if the dequeues can't be logged, the
+// items will be restored when the queue is opened again
+func (q *queueDurable) remove(n int) []*priorityqueue.Wrapper {
+	if n > len(q.data) {
+		n = len(q.data)
+	}
+	if n <= 0 {
+		return nil
+	}
+	records := make([]record, 0, n)
+	wrappers := make([]*priorityqueue.Wrapper, 0, n)
+	for _, e := range q.data[:n] {
+		records = append(records, record{Operation: operationDequeue, ID: e.id})
+		wrappers = append(wrappers, e.wrapper)
+	}
+	if err := q.write(records...); err != nil {
+		q.error(err)
+	}
+	copy(q.data, q.data[n:])
+	for i := len(q.data) - n; i < len(q.data); i++ {
+		q.data[i] = nil
+	}
+	q.data = q.data[:len(q.data)-n]
+	return wrappers
+}
+
+// enqueue will log and insert the item, it will return true if the
+// queue is full or the item couldn't be logged
+func (q *queueDurable) enqueue(item interface{}, priority int) bool {
+	return q.enqueueWrapper(&priorityqueue.Wrapper{
+		Item:       item,
+		Priority:   priority,
+		EnqueuedAt: time.Now().UnixNano(),
+	})
+}
+
+// enqueueWrapper will log and insert the wrapper, it will return true
+// if the queue is full or the wrapper couldn't be logged
+func (q *queueDurable) enqueueWrapper(wrapper *priorityqueue.Wrapper) bool {
+	if q.file == nil || len(q.data) >= q.size {
+		if q.observer != nil {
+			q.observer.Overflowed(wrapper.Priority)
+		}
+		return true
+	}
+	e := &entry{id: q.sequence + 1, wrapper: wrapper}
+	if err := q.write(record{Operation: operationEnqueue, ID: e.id, Wrapper: e.wrapper}); err != nil {
+		if q.observer != nil {
+			q.observer.Overflowed(wrapper.Priority)
+		}
+		return true
+	}
+	q.sequence = e.id
+	q.insert(e)
+	if q.observer != nil {
+		q.observer.Enqueued(e.wrapper)
+	}
+	return false
+}
+
+func (q *queueDurable) dequeued(wrappers ...*priorityqueue.Wrapper) {
+	if q.observer != nil {
+		for _, wrapper := range wrappers {
+			q.observer.Dequeued(wrapper)
+		}
+	}
+}
+
+func (q *queueDurable) evicted(wrappers ...*priorityqueue.Wrapper) {
+	if q.observer != nil {
+		for _, wrapper := range wrappers {
+			q.observer.Evicted(wrapper)
+		}
+	}
+}
+
+func (q *queueDurable) wrappers() []*priorityqueue.Wrapper {
+	wrappers := make([]*priorityqueue.Wrapper, 0, len(q.data))
+	for _, e := range q.data {
+		wrappers = append(wrappers, e.wrapper)
+	}
+	return wrappers
+}
+
+// Close will stop the queue and close the log, the items that remain in the
+// queue are returned but they also remain in the log and will be restored
+// when the queue is opened again
+func (q *queueDurable) Close() []interface{} {
+	select {
+	default:
+		close(q.stopper)
+	case <-q.stopper:
+	}
+	q.Wait()
+
+	q.Lock()
+	defer q.Unlock()
+
+	remainingElements := internal.Items(q.wrappers())
+	if q.file != nil {
+		_ = q.file.Sync()
+		_ = q.file.Close()
+	}
+	if q.signalIn != nil {
+		select {
+		default:
+			close(q.signalIn)
+		case <-q.signalIn:
+		}
+	}
+	if q.signalOut != nil {
+		select {
+		default:
+			close(q.signalOut)
+		case <-q.signalOut:
+		}
+	}
+	q.data, q.file, q.signalIn, q.signalOut = nil, nil, nil, nil
+	q.size = 0
+	return remainingElements
+}
+
+func (q *queueDurable) Compact() error {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.file == nil {
+		return os.ErrClosed
+	}
+	if q.records == len(q.data) {
+		return nil
+	}
+
+	//write the items that remain in the queue to a temporary file then
+	// replace the log with the temporary file
+	path := q.path + ".compact"
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	buffer := &bytes.Buffer{}
+	for _, e := range q.data {
+		if err := encodeRecord(buffer, q.codec, record{Operation: operationEnqueue, ID: e.id, Wrapper: e.wrapper}); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if _, err := file.Write(buffer.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := internal.Lock(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := os.Rename(path, q.path); err != nil {
+		file.Close()
+		return err
+	}
+	_ = q.file.Close()
+	q.file = file
+	q.records = len(q.data)
+	return nil
+}
+
+func (q *queueDurable) GarbageCollect() {
+	q.Lock()
+	defer q.Unlock()
+
+	data := make([]*entry, len(q.data), cap(q.data))
+	copy(data, q.data)
+	q.data = data
+}
+
+func (q *queueDurable) Resize(newSize int) []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	var discardedItems []*priorityqueue.Wrapper
+
+	if newSize < 1 {
+		newSize = 1
+	}
+	if newSize == q.size {
+		return nil
+	}
+	if len(q.data) > newSize {
+		discardedItems = q.remove(len(q.data) - newSize)
+	}
+	if q.signalIn != nil {
+		select {
+		default:
+			close(q.signalIn)
+		case <-q.signalIn:
+		}
+	}
+	if q.signalOut != nil {
+		select {
+		default:
+			close(q.signalOut)
+		case <-q.signalOut:
+		}
+	}
+	q.size = newSize
+	q.signalIn = make(chan struct{}, newSize)
+	q.signalOut = make(chan struct{}, newSize)
+	q.evicted(discardedItems...)
+	return internal.Items(discardedItems)
+}
+
+func (q *queueDurable) GetSignalIn() <-chan struct{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.signalIn
+}
+
+func (q *queueDurable) GetSignalOut() <-chan struct{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.signalOut
+}
+
+func (q *queueDurable) Dequeue() (interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	wrappers := q.remove(1)
+	if len(wrappers) == 0 {
+		return nil, true
+	}
+	q.dequeued(wrappers...)
+	internal.SendSignal(q.signalOut)
+	return wrappers[0].Item, false
+}
+
+func (q *queueDurable) DequeueMultiple(n int) []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	wrappers := q.remove(n)
+	if len(wrappers) == 0 {
+		return nil
+	}
+	q.dequeued(wrappers...)
+	internal.SendSignal(q.signalOut)
+	return internal.Items(wrappers)
+}
+
+func (q *queueDurable) Flush() []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	wrappers := q.remove(len(q.data))
+	if len(wrappers) == 0 {
+		return nil
+	}
+	q.dequeued(wrappers...)
+	internal.SendSignal(q.signalOut)
+	return internal.Items(wrappers)
+}
+
+func (q *queueDurable) Enqueue(item interface{}) bool {
+	return q.PriorityEnqueue(item, priorityqueue.ItemPriority(item, q.priorityFunc))
+}
+
+func (q *queueDurable) EnqueueMultiple(items []interface{}) ([]interface{}, bool) {
+	return q.PriorityEnqueueMultiple(items, priorityqueue.ItemPriorities(items, q.priorityFunc)...)
+}
+
+func (q *queueDurable) EnqueueLossy(item interface{}) (interface{}, bool) {
+	return q.PriorityEnqueueLossy(item, priorityqueue.ItemPriority(item, q.priorityFunc))
+}
+
+func (q *queueDurable) PriorityEnqueue(item interface{}, priorities ...int) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	priority := priorityqueue.DefaultPriority
+	if len(priorities) > 0 {
+		priority = priorities[0]
+	}
+	if overflow := q.enqueue(item, priority); overflow {
+		return true
+	}
+	internal.SendSignal(q.signalIn)
+	return false
+}
+
+func (q *queueDurable) PriorityEnqueueMultiple(items []interface{}, priorities ...int) ([]interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	var itemEnqueued bool
+
+	defer func() {
+		if itemEnqueued {
+			internal.SendSignal(q.signalIn)
+		}
+	}()
+	if len(priorities) != len(items) {
+		priority := priorityqueue.DefaultPriority
+		if len(priorities) > 0 {
+			priority = priorities[0]
+		}
+		priorities = make([]int, 0, len(items))
+		for range items {
+			priorities = append(priorities, priority)
+		}
+	}
+	for i, item := range items {
+		if overflow := q.enqueue(item, priorities[i]); overflow {
+			return items[i:], true
+		}
+		itemEnqueued = true
+	}
+	return nil, false
+}
+
+func (q *queueDurable) PriorityEnqueueLossy(item interface{}, priorities ...int) (interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	priority := priorityqueue.DefaultPriority
+	if len(priorities) > 0 {
+		priority = priorities[0]
+	}
+	if len(q.data) < q.size {
+		if overflow := q.enqueue(item, priority); overflow {
+			return nil, true
+		}
+		internal.SendSignal(q.signalIn)
+		return nil, false
+	}
+
+	//KIM: the item at the tail of the queue has the lowest priority, it's
+	// discarded if the item being enqueued has a greater or equal priority
+	tail := q.data[len(q.data)-1]
+	if q.file == nil || priority < tail.wrapper.Priority {
+		if q.observer != nil {
+			q.observer.Overflowed(priority)
+		}
+		return nil, true
+	}
+	//KIM: the item is logged before the tail is logged as dequeued (in a
+	// single write) so if the write fails, neither is logged and the queue
+	// is left unchanged
+	e := &entry{id: q.sequence + 1, wrapper: &priorityqueue.Wrapper{
+		Item:       item,
+		Priority:   priority,
+		EnqueuedAt: time.Now().UnixNano(),
+	}}
+	if err := q.write(record{Operation: operationEnqueue, ID: e.id, Wrapper: e.wrapper},
+		record{Operation: operationDequeue, ID: tail.id}); err != nil {
+		if q.observer != nil {
+			q.observer.Overflowed(priority)
+		}
+		return nil, true
+	}
+	q.sequence = e.id
+	q.data[len(q.data)-1] = nil
+	q.data = q.data[:len(q.data)-1]
+	q.evicted(tail.wrapper)
+	q.insert(e)
+	if q.observer != nil {
+		q.observer.Enqueued(e.wrapper)
+	}
+	internal.SendSignal(q.signalIn)
+	return tail.wrapper.Item, false
+}
+
+func (q *queueDurable) Length() int {
+	q.RLock()
+	defer q.RUnlock()
+
+	return len(q.data)
+}
+
+func (q *queueDurable) Capacity() int {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.size
+}
+
+func (q *queueDurable) Peek() []interface{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	items := make([]interface{}, 0, len(q.data))
+	for _, e := range q.data {
+		items = append(items, e.wrapper.Item)
+	}
+	return items
+}
+
+func (q *queueDurable) PeekHead() (interface{}, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if len(q.data) <= 0 {
+		return nil, true
+	}
+	return q.data[0].wrapper.Item, false
+}
+
+func (q *queueDurable) PeekFromHead(n int) []interface{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	if len(q.data) == 0 {
+		return nil
+	}
+	if n > len(q.data) {
+		n = len(q.data)
+	}
+	items := make([]interface{}, 0, n)
+	for _, e := range q.data[:n] {
+		items = append(items, e.wrapper.Item)
+	}
+	return items
+}
+
+func (q *queueDurable) PeekWrappers() []*priorityqueue.Wrapper {
+	q.RLock()
+	defer q.RUnlock()
+
+	wrappers := make([]*priorityqueue.Wrapper, 0, len(q.data))
+	for _, e := range q.data {
+		wrapper := *e.wrapper
+		wrappers = append(wrappers, &wrapper)
+	}
+	return wrappers
+}
+
+func (q *queueDurable) PeekWrappersFromHead(n int) []*priorityqueue.Wrapper {
+	q.RLock()
+	defer q.RUnlock()
+
+	if n > len(q.data) {
+		n = len(q.data)
+	}
+	if n < 0 {
+		n = 0
+	}
+	wrappers := make([]*priorityqueue.Wrapper, 0, n)
+	for _, e := range q.data[:n] {
+		wrapper := *e.wrapper
+		wrappers = append(wrappers, &wrapper)
+	}
+	return wrappers
+}
+
+func (q *queueDurable) PeekHeadWrapper() (*priorityqueue.Wrapper, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if len(q.data) <= 0 {
+		return nil, true
+	}
+	wrapper := *q.data[0].wrapper
+	return &wrapper, false
+}
+
+func (q *queueDurable) Reprioritize(from, to int) int {
+	q.Lock()
+	defer q.Unlock()
+
+	var records []record
+
+	if q.file == nil || from == to {
+		return 0
+	}
+
+	//KIM: an enqueue record for an id that's already in the log will
+	// replace the item when replayed, so only one record is written
+	// for each item whose priority is changed
+	for _, e := range q.data {
+		if e.wrapper.Priority == from {
+			wrapper := *e.wrapper
+			wrapper.Priority = to
+			records = append(records, record{Operation: operationEnqueue, ID: e.id, Wrapper: &wrapper})
+		}
+	}
+	if len(records) == 0 {
+		return 0
+	}
+	if err := q.write(records...); err != nil {
+		return 0
+	}
+	for _, e := range q.data {
+		if e.wrapper.Priority == from {
+			e.wrapper.Priority = to
+			priorityqueue.NotifyReprioritized(q.observer, e.wrapper, from)
+		}
+	}
+	sort.SliceStable(q.data, func(i, j int) bool { return less(q.data[i], q.data[j]) })
+	return len(records)
+}
+
+func (q *queueDurable) Snapshot(w io.Writer) error {
+	q.RLock()
+	defer q.RUnlock()
+
+	return priorityqueue.WriteSnapshot(w, q.wrappers())
+}
+
+func (q *queueDurable) Restore(r io.Reader) error {
+	wrappers, err := priorityqueue.ReadSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if q.file == nil {
+		return os.ErrClosed
+	}
+	if len(wrappers) > q.size {
+		return priorityqueue.ErrSnapshotOverflow
+	}
+	q.evicted(q.remove(len(q.data))...)
+	for _, wrapper := range wrappers {
+		e := &entry{id: q.sequence + 1, wrapper: wrapper}
+		if err := q.write(record{Operation: operationEnqueue, ID: e.id, Wrapper: wrapper}); err != nil {
+			return err
+		}
+		q.sequence = e.id
+		q.insert(e)
+		if q.observer != nil {
+			q.observer.Enqueued(wrapper)
+		}
+	}
+	if len(wrappers) > 0 {
+		internal.SendSignal(q.signalIn)
+	}
+	return nil
+}
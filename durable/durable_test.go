@@ -0,0 +1,425 @@
+package prioritydurable_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	goqueueprioritydurable "github.com/antonio-alexander/go-queue-priority/durable"
+	goqueuepriorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	metrics "github.com/antonio-alexander/go-queue-priority/metrics"
+	finite "github.com/antonio-alexander/go-queue/finite"
+
+	goqueuepriorityfinite_tests "github.com/antonio-alexander/go-queue-priority/finite/tests"
+	finite_tests "github.com/antonio-alexander/go-queue/finite/tests"
+	goqueue_tests "github.com/antonio-alexander/go-queue/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	mustTimeout time.Duration = time.Second
+	mustRate    time.Duration = time.Millisecond
+)
+
+func open(t *testing.T, size int, parameters ...interface{}) interface {
+	goqueue.Owner
+	goqueue.Length
+	goqueue.Event
+	goqueue.Peeker
+	goqueue.Dequeuer
+	goqueue.Enqueuer
+	goqueue.GarbageCollecter
+	finite.Resizer
+	finite.Capacity
+	goqueuepriority.PriorityEnqueuer
+	goqueuepriority.WrapperPeeker
+	goqueuepriority.Snapshotter
+	goqueuepriorityfinite.PriorityEnqueueLossy
+	goqueueprioritydurable.Compacter
+} {
+	q, err := goqueueprioritydurable.Open(filepath.Join(t.TempDir(), "queue.log"), size, parameters...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestDurableFiniteQueue(t *testing.T) {
+	t.Run("Test Enqueue", finite_tests.TestEnqueue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Enqueue Multiple", finite_tests.TestEnqueueMultiple(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Enqueue Event", finite_tests.TestEnqueueEvent(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Event
+	} {
+		return open(t, size)
+	}))
+
+	t.Run("Test Resize", finite_tests.TestResize(t, func(size int) interface {
+		finite.Capacity
+		goqueue.Enqueuer
+		goqueue.Owner
+		finite.Resizer
+	} {
+		return open(t, size)
+	}))
+	//REVIEW: how to fix this functionality?
+	// t.Run("Test Enqueue Lossy", finite_tests.TestEnqueueLossy(t, func(size int) interface {
+	// 	goqueue.Owner
+	// 	finite.EnqueueLossy
+	// } {
+	// 	return open(t, size)
+	// }))
+	t.Run("Test Capacity", finite_tests.TestCapacity(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		finite.Capacity
+	} {
+		return open(t, size)
+	}))
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("Test Dequeue", goqueue_tests.TestDequeue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Dequeue Event", goqueue_tests.TestDequeueEvent(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Dequeuer
+		goqueue.Enqueuer
+		goqueue.Event
+		goqueue.Owner
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Dequeue Multiple", goqueue_tests.TestDequeueMultiple(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Flush", goqueue_tests.TestFlush(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Peek", goqueue_tests.TestPeek(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Peeker
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Peek From Head", goqueue_tests.TestPeekFromHead(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Peeker
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Length", goqueue_tests.TestLength(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Length
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Garbage Collect", goqueue_tests.TestGarbageCollect(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.GarbageCollecter
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	//
+	t.Run("Test Queue", goqueue_tests.TestQueue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Asynchronous", goqueue_tests.TestAsync(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+}
+
+func TestPriorityDurableQueue(t *testing.T) {
+	t.Run("Test Priority Enqueue", goqueuepriorityfinite_tests.TestPriorityEnqueue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueuepriority.PriorityEnqueuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Priority Enqueue Event", goqueuepriorityfinite_tests.TestPriorityEnqueueEvent(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueue.Event
+		goqueuepriority.PriorityEnqueuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Priority Enqueue Lossy", goqueuepriorityfinite_tests.TestPriorityEnqueueLossy(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueuepriority.PriorityEnqueuer
+		goqueuepriorityfinite.PriorityEnqueueLossy
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Priority Enqueue Lossy Event", goqueuepriorityfinite_tests.TestPriorityEnqueueLossyEvent(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueue.Event
+		goqueuepriority.PriorityEnqueuer
+		goqueuepriorityfinite.PriorityEnqueueLossy
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Snapshot", goqueuepriorityfinite_tests.TestSnapshot(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueuepriority.PriorityEnqueuer
+		goqueuepriority.WrapperPeeker
+		goqueuepriority.Snapshotter
+	} {
+		return open(t, size)
+	}))
+}
+
+func TestDurableReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.log")
+	q, err := goqueueprioritydurable.Open(path, 10, goqueueprioritydurable.Configuration{
+		Fsync:           goqueueprioritydurable.FsyncNever,
+		CompactInterval: -1,
+	})
+	assert.Nil(t, err)
+	for i, priority := range []int{1, 2, 1, 3, 2} {
+		assert.False(t, q.PriorityEnqueue(float64(i), priority))
+	}
+	item, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, float64(3), item)
+	wrappers := q.PeekWrappers()
+	expected := []interface{}{float64(1), float64(4), float64(0), float64(2)}
+	assert.Equal(t, expected, q.Close())
+
+	//simulate a torn write at the end of the log
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	_, err = file.Write([]byte{0, 0, 0, 42, 'e', 7})
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	//re-open the queue and validate that the items, their
+	// priorities and the order are restored
+	q, err = goqueueprioritydurable.Open(path, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, q.Capacity())
+	assert.Equal(t, wrappers, q.PeekWrappers())
+	assert.Equal(t, expected[:2], q.DequeueMultiple(2))
+	q.Close()
+
+	//re-open the queue and compact the log
+	q, err = goqueueprioritydurable.Open(path, 4)
+	assert.Nil(t, err)
+	infoBefore, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Nil(t, q.Compact())
+	infoAfter, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Less(t, infoAfter.Size(), infoBefore.Size())
+	assert.False(t, q.PriorityEnqueue(float64(5), 5))
+	q.Close()
+	q, err = goqueueprioritydurable.Open(path, 4, goqueueprioritydurable.Configuration{
+		Fsync:         goqueueprioritydurable.FsyncInterval,
+		FsyncInterval: time.Millisecond,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{float64(5), float64(0), float64(2)}, q.Flush())
+	q.Close()
+}
+
+// TestDurableLossy is meant to confirm that when an item is enqueued with
+// loss, the item replaces the tail of the queue within the log
+func TestDurableLossy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.log")
+	q, err := goqueueprioritydurable.Open(path, 2)
+	assert.Nil(t, err)
+	assert.False(t, q.PriorityEnqueue("a", 1))
+	assert.False(t, q.PriorityEnqueue("b", 2))
+	discarded, overflow := q.PriorityEnqueueLossy("c", 3)
+	assert.False(t, overflow)
+	assert.Equal(t, "a", discarded)
+	discarded, overflow = q.PriorityEnqueueLossy("d", 0)
+	assert.True(t, overflow)
+	assert.Nil(t, discarded)
+	q.Close()
+
+	//re-open the queue and validate that the tail was replaced
+	q, err = goqueueprioritydurable.Open(path, 2)
+	assert.Nil(t, err)
+	defer q.Close()
+	assert.Equal(t, []interface{}{"c", "b"}, q.Flush())
+}
+
+// TestDurableErrors is meant to confirm that errors that can't be returned
+// (e.g. periodic compaction) are provided to the error handler
+func TestDurableErrors(t *testing.T) {
+	errs := make(chan error, 1)
+	path := filepath.Join(t.TempDir(), "queue.log")
+	assert.Nil(t, os.Mkdir(path+".compact", 0755))
+	q, err := goqueueprioritydurable.Open(path, 2, goqueueprioritydurable.Configuration{
+		CompactInterval: time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	assert.Nil(t, err)
+	defer q.Close()
+	assert.False(t, q.PriorityEnqueue("a", 1))
+	_, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(mustTimeout):
+		assert.Fail(t, "timed out waiting for error")
+	}
+}
+
+func TestDurableReprioritize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.log")
+	q, err := goqueueprioritydurable.Open(path, 3)
+	assert.Nil(t, err)
+	assert.False(t, q.PriorityEnqueue("old", 1))
+	assert.False(t, q.PriorityEnqueue("urgent", 2))
+	assert.False(t, q.PriorityEnqueue("newer", 1))
+	assert.Equal(t, 2, q.Reprioritize(1, 3))
+	expected := []interface{}{"old", "newer", "urgent"}
+	assert.Equal(t, expected, q.Peek())
+	q.Close()
+
+	//re-open the queue and validate that the priorities are restored
+	q, err = goqueueprioritydurable.Open(path, 3)
+	assert.Nil(t, err)
+	defer q.Close()
+	wrappers := q.PeekWrappers()
+	assert.Equal(t, expected, q.Peek())
+	assert.Equal(t, []int{3, 3, 2}, []int{wrappers[0].Priority, wrappers[1].Priority, wrappers[2].Priority})
+}
+
+// TestDurableReprioritizeMetrics is meant to confirm that the depth of each
+// priority is maintained when items are reprioritized
+func TestDurableReprioritizeMetrics(t *testing.T) {
+	collector := metrics.New()
+	q, err := goqueueprioritydurable.Open(filepath.Join(t.TempDir(), "queue.log"), 3, collector)
+	assert.Nil(t, err)
+	defer q.Close()
+
+	assert.False(t, q.PriorityEnqueue("a", 1))
+	assert.Equal(t, 1, q.Reprioritize(1, 5))
+	for _, m := range collector.Snapshot() {
+		assert.Equal(t, map[int]int64{1: 0, 5: 1}[m.Priority], m.Depth, m.Priority)
+	}
+	_, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	for _, m := range collector.Snapshot() {
+		assert.Equal(t, int64(0), m.Depth, m.Priority)
+	}
+}
+
+func TestDurableCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.log")
+	q, err := goqueueprioritydurable.Open(path, 2)
+	assert.Nil(t, err)
+	assert.False(t, q.PriorityEnqueue("a", 1))
+	assert.False(t, q.PriorityEnqueue("b", 1))
+	q.Close()
+
+	//corrupt the first record, since it's followed by another
+	// record it can't be a torn write
+	bytes, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	bytes[5] ^= 0xff
+	err = os.WriteFile(path, bytes, 0644)
+	assert.Nil(t, err)
+	_, err = goqueueprioritydurable.Open(path, 1)
+	assert.NotNil(t, err)
+}
+
+// TestDurableLock is meant to confirm that a log can only be opened once
+// and that a log that's open can still be read
+func TestDurableLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.log")
+	q, err := goqueueprioritydurable.Open(path, 4, goqueueprioritydurable.Configuration{
+		CompactInterval: -1,
+	})
+	assert.Nil(t, err)
+	assert.False(t, q.PriorityEnqueue("a", 1))
+	assert.False(t, q.PriorityEnqueue("b", 2))
+	assert.False(t, q.PriorityEnqueue("c", 1))
+	_, err = goqueueprioritydurable.Open(path, 4)
+	assert.ErrorIs(t, err, goqueueprioritydurable.ErrLocked)
+
+	//validate that the log can be read while it's open and that
+	// reading it doesn't modify it
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	wrappers, err := goqueueprioritydurable.ReadLog(path)
+	assert.Nil(t, err)
+	assert.Equal(t, q.PeekWrappers(), wrappers)
+	infoRead, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Size(), infoRead.Size())
+
+	//validate that the log remains locked once compacted
+	_, _ = q.Dequeue()
+	assert.Nil(t, q.Compact())
+	_, err = goqueueprioritydurable.Open(path, 4)
+	assert.ErrorIs(t, err, goqueueprioritydurable.ErrLocked)
+	q.Close()
+
+	//validate that the log can be opened once it's closed
+	q, err = goqueueprioritydurable.Open(path, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "c"}, q.Flush())
+	q.Close()
+	_, err = goqueueprioritydurable.ReadLog(filepath.Join(t.TempDir(), "missing.log"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
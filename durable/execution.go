@@ -0,0 +1,79 @@
+package prioritydurable
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	priorityqueue "github.com/antonio-alexander/go-queue-priority"
+)
+
+// errChecksum is returned when the checksum of a record doesn't match
+var errChecksum = errors.New("record checksum mismatch")
+
+// encodeRecord will append the record to the buffer, each record is prefixed by
+// its length (a big endian uint32) and followed by its checksum (crc32)
+func encodeRecord(buffer *bytes.Buffer, codec priorityqueue.Codec, r record) error {
+	payload := []byte{r.Operation}
+	payload = binary.AppendUvarint(payload, r.ID)
+	if r.Operation == operationEnqueue {
+		envelope, err := priorityqueue.MarshalWrapper(codec, r.Wrapper)
+		if err != nil {
+			return err
+		}
+		payload = append(payload, envelope...)
+	}
+	frame := make([]byte, 4, 8+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	frame = binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(payload))
+	_, err := buffer.Write(frame)
+	return err
+}
+
+// decodeRecord will read a single record, it will return io.EOF if there are no
+// more records and io.ErrUnexpectedEOF if the record is incomplete; the number of
+// bytes read is returned
+func decodeRecord(reader io.Reader, codec priorityqueue.Codec) (*record, int64, error) {
+	prefix := make([]byte, 4)
+	if n, err := io.ReadFull(reader, prefix); err != nil {
+		if n > 0 {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	payload := &bytes.Buffer{}
+	length := int64(binary.BigEndian.Uint32(prefix))
+	if _, err := io.CopyN(payload, reader, length+4); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	data := payload.Bytes()
+	checksum := binary.BigEndian.Uint32(data[length:])
+	data = data[:length]
+	if len(data) == 0 || crc32.ChecksumIEEE(data) != checksum {
+		return nil, 0, errChecksum
+	}
+	r := &record{Operation: data[0]}
+	id, n := binary.Uvarint(data[1:])
+	if n <= 0 {
+		return nil, 0, errChecksum
+	}
+	r.ID = id
+	switch r.Operation {
+	default:
+		return nil, 0, errChecksum
+	case operationDequeue:
+	case operationEnqueue:
+		wrapper, err := priorityqueue.UnmarshalWrapper(codec, data[1+n:])
+		if err != nil {
+			return nil, 0, err
+		}
+		r.Wrapper = wrapper
+	}
+	return r, 4 + length + 4, nil
+}
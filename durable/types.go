@@ -0,0 +1,81 @@
+package prioritydurable
+
+import (
+	"time"
+
+	priorityqueue "github.com/antonio-alexander/go-queue-priority"
+	internal "github.com/antonio-alexander/go-queue-priority/internal"
+)
+
+// ErrLocked is returned by Open when the log is already open (e.g. by
+// another process)
+var ErrLocked = internal.ErrLocked
+
+// FsyncPolicy describes when the log is synced to disk
+type FsyncPolicy int
+
+const (
+	//FsyncAlways will sync the log to disk after every write
+	FsyncAlways FsyncPolicy = iota
+
+	//FsyncInterval will sync the log to disk periodically
+	FsyncInterval
+
+	//FsyncNever will never explicitly sync the log to disk and rely
+	// on the operating system
+	FsyncNever
+)
+
+// DefaultFsyncInterval is the interval the log is synced to disk when
+// using FsyncInterval if no interval is configured
+const DefaultFsyncInterval time.Duration = time.Second
+
+// DefaultCompactInterval is the interval at which the log is compacted
+// if no interval is configured
+const DefaultCompactInterval time.Duration = time.Minute
+
+// Configuration can be provided to Open to configure how the log is
+// synced and compacted
+type Configuration struct {
+	//Fsync is the policy used to sync the log to disk
+	Fsync FsyncPolicy
+
+	//FsyncInterval is the interval used for FsyncInterval
+	FsyncInterval time.Duration
+
+	//CompactInterval is the interval at which the log is compacted,
+	// a negative interval will disable periodic compaction
+	CompactInterval time.Duration
+
+	//OnError, if provided, is called with errors that can't otherwise be
+	// returned (e.g. failing to log dequeues or to compact periodically),
+	// it may be called while the queue is locked so it must not use the
+	// queue
+	OnError func(err error)
+}
+
+// Compacter can be used to rewrite the log such that it only contains
+// the items that remain in the queue
+type Compacter interface {
+	Compact() (err error)
+}
+
+const (
+	operationEnqueue byte = 'e'
+	operationDequeue byte = 'd'
+)
+
+// record describes a single entry in the log, enqueue records contain
+// the wrapper of the item while dequeue records only contain the id
+type record struct {
+	Operation byte
+	ID        uint64
+	Wrapper   *priorityqueue.Wrapper
+}
+
+// entry is an item in the queue along with the id used to identify
+// it within the log
+type entry struct {
+	id      uint64
+	wrapper *priorityqueue.Wrapper
+}
@@ -0,0 +1,178 @@
+package priorityfinite
+
+import (
+	"math"
+	"time"
+
+	priorityqueue "github.com/antonio-alexander/go-queue-priority"
+)
+
+// schedule maintains the state used to determine the next item to
+// dequeue when dequeuing isn't strictly by priority
+type schedule struct {
+	credits map[int]int    //credits for each priority (weights)
+	tenants map[int]string //tenant last dequeued for each priority
+}
+
+func newSchedule() schedule {
+	return schedule{
+		credits: make(map[int]int),
+		tenants: make(map[int]string),
+	}
+}
+
+// copy will return a copy of the schedule
+func (s schedule) copy() schedule {
+	c := newSchedule()
+	for priority, credit := range s.credits {
+		c.credits[priority] = credit
+	}
+	for priority, tenant := range s.tenants {
+		c.tenants[priority] = tenant
+	}
+	return c
+}
+
+// weightedNext will return the index of the first item of the priority to be
+// dequeued next using smooth weighted round robin, it'll update the credits
+// for each priority present; priorities that aren't present will have their
+// credits reset. This works off the idea that data is sorted such that items
+// with the same priority are contiguous
+func weightedNext(data []*priorityqueue.Wrapper, weights Weights, credits map[int]int) int {
+	var total, best int
+
+	present := make(map[int]int)
+	bestPriority, found := 0, false
+	for i, wrapper := range data {
+		if _, ok := present[wrapper.Priority]; ok {
+			continue
+		}
+		present[wrapper.Priority] = i
+		weight, ok := weights[wrapper.Priority]
+		if !ok || weight < 1 {
+			weight = 1
+		}
+		total += weight
+		credits[wrapper.Priority] += weight
+		if credit := credits[wrapper.Priority]; !found || credit > best ||
+			(credit == best && wrapper.Priority > bestPriority) {
+			best, bestPriority, found = credit, wrapper.Priority, true
+		}
+	}
+	for priority := range credits {
+		if _, ok := present[priority]; !ok {
+			delete(credits, priority)
+		}
+	}
+	if !found {
+		return -1
+	}
+	credits[bestPriority] -= total
+	return present[bestPriority]
+}
+
+// tenantNext will return the index of the oldest item of the tenant to be
+// dequeued next within the priority of the item at the given index; tenants
+// are selected in round robin (ordered by name) after the tenant that was last
+// dequeued for that priority. This works off the idea that data is sorted such
+// that items with the same priority are contiguous and in the order they were
+// enqueued
+func tenantNext(data []*priorityqueue.Wrapper, index int, tenants map[int]string) int {
+	var next, first string
+	var foundNext, foundFirst bool
+
+	priority := data[index].Priority
+	last, ok := tenants[priority]
+	oldest := make(map[string]int)
+	for i := index; i < len(data) && data[i].Priority == priority; i++ {
+		tenant := data[i].Tenant
+		if _, found := oldest[tenant]; found {
+			continue
+		}
+		oldest[tenant] = i
+		if !foundFirst || tenant < first {
+			first, foundFirst = tenant, true
+		}
+		if ok && tenant > last && (!foundNext || tenant < next) {
+			next, foundNext = tenant, true
+		}
+	}
+	if !foundNext {
+		next = first
+	}
+	tenants[priority] = next
+	return oldest[next]
+}
+
+// next will return the index of the next item to be dequeued
+// and update the schedule
+func next(data []*priorityqueue.Wrapper, weights Weights, fair bool, s schedule) int {
+	index := 0
+	if weights != nil {
+		index = weightedNext(data, weights, s.credits)
+	}
+	if fair {
+		index = tenantNext(data, index, s.tenants)
+	}
+	return index
+}
+
+// validate will return the watermarks adjusted for the given capacity,
+// a high watermark greater than the capacity is reduced to the capacity,
+// a negative low watermark is increased to zero and if the low watermark
+// isn't less than the high watermark, the watermarks are disabled
+func (w Watermarks) validate(capacity int) Watermarks {
+	if w.High > capacity {
+		w.High = capacity
+	}
+	if w.Low < 0 {
+		w.Low = 0
+	}
+	if w.Low >= w.High {
+		w.High = 0
+	}
+	return w
+}
+
+// admit will return true if an item with the given priority should be
+// admitted given the length and capacity of the queue
+func (a AdmissionSteps) admit(priority, length, capacity int) bool {
+	var step *AdmissionStep
+
+	fill := float64(length) / float64(capacity)
+	for i := range a {
+		if fill >= a[i].Fill && (step == nil || a[i].Fill > step.Fill) {
+			step = &a[i]
+		}
+	}
+	return step == nil || priority >= step.MinPriority
+}
+
+// controller maintains the state used to drop items using controlled
+// delay (see RFC 8289)
+type controller struct {
+	firstAboveTime time.Time //when the time in queue will have exceeded the target for an interval
+	dropNext       time.Time //when the next item should be dropped
+	count          int       //number of items dropped since dropping started
+	dropping       bool      //true if items are being dropped
+}
+
+// okToDrop will return true if the time the oldest item that can be dropped
+// has been in the queue has exceeded the target for at least an interval
+func (c *controller) okToDrop(config CoDel, now time.Time, oldest *priorityqueue.Wrapper) bool {
+	if oldest == nil || now.Sub(time.Unix(0, oldest.EnqueuedAt)) < config.Target {
+		c.firstAboveTime = time.Time{}
+		return false
+	}
+	if c.firstAboveTime.IsZero() {
+		c.firstAboveTime = now.Add(config.Interval)
+		return false
+	}
+	return !now.Before(c.firstAboveTime)
+}
+
+// controlLaw will return when the next item should be dropped, the time
+// between drops decreases with the square root of the number of drops
+func (c *controller) controlLaw(config CoDel, t time.Time) time.Time {
+	return t.Add(time.Duration(float64(config.Interval) / math.Sqrt(float64(c.count))))
+}
@@ -1,6 +1,7 @@
 package priorityfinite
 
 import (
+	"io"
 	"sort"
 	"sync"
 	"time"
@@ -14,12 +15,53 @@ import (
 
 type queueFinite struct {
 	sync.RWMutex
-	signalIn  chan struct{}
-	signalOut chan struct{}
-	data      []*priorityqueue.Wrapper
+	signalIn     chan struct{}
+	signalOut    chan struct{}
+	signalHigh   chan struct{}
+	signalLow    chan struct{}
+	data         []*priorityqueue.Wrapper
+	observer     priorityqueue.Observer
+	priorityFunc priorityqueue.PriorityFunc
+	weights      Weights
+	schedule     schedule
+	tenants      map[string]int
+	tenantCap    TenantCapacity
+	dynamic      DynamicPriority
+	rescoredAt   time.Time
+	levels       map[int]int
+	keyed        int
+	stats        Stats
+	watermarks   Watermarks
+	configured   Watermarks
+	high         bool
+	admission    AdmissionFunc
+	clock        Clock
+	codel        CoDel
+	controller   controller
 }
 
-func New(size int) interface {
+// New can be used to create a finite priority queue with the given size, the
+// optional parameters can be used to further configure the queue:
+//   - priorityqueue.Observer: will be notified as items move through the queue
+//   - priorityqueue.PriorityFunc: determines the priority of items enqueued using
+//     Enqueue, otherwise items that implement priorityqueue.Prioritizer provide their own
+//     priority (items enqueued using PriorityEnqueue without a priority have the
+//     default priority)
+//   - Weights: will dequeue using weighted round robin between priorities
+//   - TenantCapacity: will limit the number of items each tenant can enqueue
+//   - DynamicPriority: will re-evaluate the priority of items before they're dequeued
+//   - Watermarks: will signal when the number of items crosses the high and low watermarks
+//   - AdmissionSteps or AdmissionFunc: will reject items with a low priority as the queue
+//     fills up
+//   - Clock: will be used to determine the current time instead of time.Now
+//   - CoDel: will drop items with a low priority when items spend too long in the queue
+//
+// Items are stored in a slice sorted by priority: enqueueing an item finds its
+// position using a binary search but shifts the items after it and dequeueing
+// from the head shifts the remaining items, so both are O(n), while dequeueing
+// from the tail is O(1); rescoring, reprioritizing and restoring items sort
+// every item (O(n log n))
+func New(size int, parameters ...interface{}) interface {
 	goqueue.Owner
 	goqueue.GarbageCollecter
 	goqueue.Length
@@ -31,29 +73,531 @@ func New(size int) interface {
 	finite.Resizer
 	finite.Capacity
 	priorityqueue.PriorityEnqueuer
+	priorityqueue.TenantEnqueuer
+	priorityqueue.KeyEnqueuer
+	priorityqueue.WrapperDequeuer
+	priorityqueue.WrapperEnqueuer
+	priorityqueue.TailDequeuer
+	priorityqueue.ConditionalDequeuer
+	priorityqueue.WrapperPeeker
+	priorityqueue.WrapperHeadPeeker
+	priorityqueue.Snapshotter
+	priorityqueue.Reprioritizer
+	priorityqueue.Rescorer
 	PriorityEnqueueLossy
+	Statistics
+	WatermarkEvent
+	TryEnqueuer
 } {
 	if size < 1 {
 		size = 1
 	}
-	return &queueFinite{
-		signalIn:  make(chan struct{}, size),
-		signalOut: make(chan struct{}, size),
-		data:      make([]*priorityqueue.Wrapper, 0, size),
+	q := &queueFinite{
+		signalIn:   make(chan struct{}, size),
+		signalOut:  make(chan struct{}, size),
+		signalHigh: make(chan struct{}, 1),
+		signalLow:  make(chan struct{}, 1),
+		data:       make([]*priorityqueue.Wrapper, 0, size),
+		schedule:   newSchedule(),
+		tenants:    make(map[string]int),
+		levels:     make(map[int]int),
 	}
+	for _, parameter := range parameters {
+		switch p := parameter.(type) {
+		case priorityqueue.Observer:
+			q.observer = p
+		case priorityqueue.PriorityFunc:
+			q.priorityFunc = p
+		case func(item interface{}) int:
+			q.priorityFunc = p
+		case Weights:
+			q.weights = p
+		case TenantCapacity:
+			q.tenantCap = p
+		case DynamicPriority:
+			q.dynamic = p
+		case Watermarks:
+			q.configured = p
+			q.watermarks = p.validate(size)
+		case AdmissionSteps:
+			q.admission = p.admit
+		case AdmissionFunc:
+			q.admission = p
+		case func(priority, length, capacity int) bool:
+			q.admission = p
+		case Clock:
+			q.clock = p
+		case func() time.Time:
+			q.clock = p
+		case CoDel:
+			q.codel = p
+		}
+	}
+	return q
+}
+
+// dequeue will remove up to n items in the order they should be dequeued
+// and return them, it will return true if there are no items to dequeue
+func (q *queueFinite) dequeue(n int) ([]*priorityqueue.Wrapper, bool) {
+	var wrappers []*priorityqueue.Wrapper
+	var underflow bool
+
+	q.rescore()
+	if !q.scheduled() {
+		wrappers, q.data, underflow = internal.DequeueMultiple(n, q.data)
+		return wrappers, underflow
+	}
+	if len(q.data) == 0 {
+		return nil, true
+	}
+	if n > len(q.data) {
+		n = len(q.data)
+	}
+	wrappers = make([]*priorityqueue.Wrapper, 0, n)
+	for i := 0; i < n; i++ {
+		var wrapper *priorityqueue.Wrapper
+
+		wrapper, q.data = internal.Remove(q.data, next(q.data, q.weights, q.fair(), q.schedule))
+		wrappers = append(wrappers, wrapper)
+	}
+	return wrappers, false
+}
+
+// peek will return up to n wrappers in the order they should be
+// dequeued without modifying the queue
+func (q *queueFinite) peek(n int) []*priorityqueue.Wrapper {
+	if n > len(q.data) {
+		n = len(q.data)
+	}
+	if !q.scheduled() {
+		return q.data[:n]
+	}
+	data := append(make([]*priorityqueue.Wrapper, 0, len(q.data)), q.data...)
+	schedule := q.schedule.copy()
+	wrappers := make([]*priorityqueue.Wrapper, 0, n)
+	for i := 0; i < n; i++ {
+		var wrapper *priorityqueue.Wrapper
+
+		wrapper, data = internal.Remove(data, next(data, q.weights, q.fair(), schedule))
+		wrappers = append(wrappers, wrapper)
+	}
+	return wrappers
+}
+
+// dequeueTail will remove up to n items from the tail of the queue and
+// return them, it will return true if there are no items to dequeue
+func (q *queueFinite) dequeueTail(n int) ([]*priorityqueue.Wrapper, bool) {
+	q.rescore()
+	if len(q.data) == 0 {
+		return nil, true
+	}
+	wrappers := q.peekTail(n)
+	for i := len(q.data) - len(wrappers); i < len(q.data); i++ {
+		q.data[i] = nil
+	}
+	q.data = q.data[:len(q.data)-len(wrappers)]
+	return wrappers, false
+}
+
+// peekTail will return up to n wrappers from the tail of the queue
+// without modifying the queue
+func (q *queueFinite) peekTail(n int) []*priorityqueue.Wrapper {
+	if n > len(q.data) {
+		n = len(q.data)
+	}
+	if n < 0 {
+		n = 0
+	}
+	wrappers := make([]*priorityqueue.Wrapper, 0, n)
+	for i := len(q.data) - 1; i >= len(q.data)-n; i-- {
+		wrappers = append(wrappers, q.data[i])
+	}
+	return wrappers
+}
+
+// dequeueIf will remove up to n items for which fn returns true and return
+// them, the order of the remaining items is preserved
+func (q *queueFinite) dequeueIf(n int, fn func(item interface{}, priority int) bool) []*priorityqueue.Wrapper {
+	var i int
+
+	q.rescore()
+	wrappers := q.peekIf(n, fn)
+	if len(wrappers) == 0 {
+		return nil
+	}
+	data := q.data[:0]
+	for _, wrapper := range q.data {
+		if i < len(wrappers) && wrapper == wrappers[i] {
+			i++
+			continue
+		}
+		data = append(data, wrapper)
+	}
+	for j := len(data); j < len(q.data); j++ {
+		q.data[j] = nil
+	}
+	q.data = data
+	return wrappers
+}
+
+// peekIf will return up to n wrappers for which fn returns true in
+// order of priority without modifying the queue
+func (q *queueFinite) peekIf(n int, fn func(item interface{}, priority int) bool) []*priorityqueue.Wrapper {
+	var wrappers []*priorityqueue.Wrapper
+
+	for _, wrapper := range q.data {
+		if len(wrappers) >= n {
+			break
+		}
+		if fn(wrapper.Item, wrapper.Priority) {
+			wrappers = append(wrappers, wrapper)
+		}
+	}
+	return wrappers
+}
+
+// atLeast will return a function that returns true for items with
+// a priority of at least minPriority
+func atLeast(minPriority int) func(item interface{}, priority int) bool {
+	return func(_ interface{}, priority int) bool {
+		return priority >= minPriority
+	}
+}
+
+// now will return the current time
+func (q *queueFinite) now() time.Time {
+	if q.clock != nil {
+		return q.clock()
+	}
+	return time.Now()
+}
+
+// control will drop items using controlled delay if the time items have
+// been in the queue has exceeded the target for at least an interval, the
+// time in queue is measured again after each item is dropped
+func (q *queueFinite) control() {
+	if q.codel.Target <= 0 || q.codel.Interval <= 0 {
+		return
+	}
+	now := q.now()
+	oldest := q.droppable()
+	okToDrop := q.controller.okToDrop(q.codel, now, oldest)
+	if !q.controller.dropping {
+		if !okToDrop {
+			return
+		}
+		q.drop(oldest)
+
+		//KIM: if dropping stopped recently, resume dropping at
+		// close to the rate it stopped at
+		q.controller.dropping = true
+		if q.controller.count > 2 && now.Sub(q.controller.dropNext) < 16*q.codel.Interval {
+			q.controller.count -= 2
+		} else {
+			q.controller.count = 1
+		}
+		q.controller.dropNext = q.controller.controlLaw(q.codel, now)
+		return
+	}
+	if !okToDrop {
+		q.controller.dropping = false
+		return
+	}
+	for !now.Before(q.controller.dropNext) {
+		q.drop(oldest)
+		q.controller.count++
+		oldest = q.droppable()
+		if !q.controller.okToDrop(q.codel, now, oldest) {
+			q.controller.dropping = false
+			return
+		}
+		q.controller.dropNext = q.controller.controlLaw(q.codel, q.controller.dropNext)
+	}
+}
+
+// droppable will return the oldest wrapper with a priority that can be
+// dropped, the time it has been in the queue is used to determine if
+// items should be dropped
+func (q *queueFinite) droppable() *priorityqueue.Wrapper {
+	var oldest *priorityqueue.Wrapper
+
+	i := sort.Search(len(q.data), func(i int) bool {
+		return q.data[i].Priority <= q.codel.DropPriority
+	})
+	for _, wrapper := range q.data[i:] {
+		if oldest == nil || wrapper.EnqueuedAt < oldest.EnqueuedAt {
+			oldest = wrapper
+		}
+	}
+	return oldest
+}
+
+// drop will remove the wrapper (returned by droppable) from the queue
+func (q *queueFinite) drop(wrapper *priorityqueue.Wrapper) {
+	for i := range q.data {
+		if q.data[i] != wrapper {
+			continue
+		}
+		copy(q.data[i:], q.data[i+1:])
+		q.data[len(q.data)-1] = nil
+		q.data = q.data[:len(q.data)-1]
+		break
+	}
+	q.evicted(wrapper)
+	if q.codel.OnDrop != nil {
+		q.codel.OnDrop(wrapper.Item, wrapper.Priority)
+	}
+}
+
+// rescore will re-evaluate the priority of every item if the queue has a
+// dynamic priority and the interval has elapsed since items were last
+// rescored, it returns the number of items whose priority changed
+func (q *queueFinite) rescore() int {
+	var n int
+
+	if q.dynamic.Score == nil {
+		return 0
+	}
+	now := q.now()
+	if !q.rescoredAt.IsZero() && now.Sub(q.rescoredAt) < q.dynamic.Interval {
+		return 0
+	}
+	q.rescoredAt = now
+	for _, wrapper := range q.data {
+		if priority := q.dynamic.Score(wrapper.Item); priority != wrapper.Priority {
+			q.reprioritized(wrapper, priority)
+			n++
+		}
+	}
+	if n > 0 {
+		q.sort()
+	}
+	return n
+}
+
+// sort will sort the items in the order they should be dequeued, items
+// with the same priority (and key) remain in the order they were enqueued
+// insert will insert the wrapper after every item that would be dequeued
+// before it (the items must already be sorted), it will return true if
+// the queue is full
+func (q *queueFinite) insert(wrapper *priorityqueue.Wrapper) bool {
+	if len(q.data) >= cap(q.data) {
+		return true
+	}
+	i := sort.Search(len(q.data), func(i int) bool {
+		return priorityqueue.Less(wrapper, q.data[i])
+	})
+	q.data = append(q.data, nil)
+	copy(q.data[i+1:], q.data[i:])
+	q.data[i] = wrapper
+	return false
+}
+
+func (q *queueFinite) sort() {
+	sort.SliceStable(q.data, func(i, j int) bool {
+		return priorityqueue.Less(q.data[i], q.data[j])
+	})
+}
+
+// fair will return true if items have been enqueued with a tenant, if
+// so dequeues within a priority should round robin between tenants
+func (q *queueFinite) fair() bool {
+	return len(q.tenants) > 0
+}
+
+// scheduled will return true if items aren't dequeued strictly
+// by priority
+func (q *queueFinite) scheduled() bool {
+	return q.weights != nil || q.fair()
+}
+
+// tenantFull will return true if the tenant has reached its capacity
+func (q *queueFinite) tenantFull(tenant string) bool {
+	return tenant != "" && q.tenantCap > 0 && q.tenants[tenant] >= int(q.tenantCap)
+}
+
+// removed will update the number of items for each tenant and priority
+func (q *queueFinite) removed(wrappers ...*priorityqueue.Wrapper) {
+	for _, wrapper := range wrappers {
+		q.leveled(wrapper.Priority, -1)
+		if len(wrapper.Key) > 0 {
+			q.keyed--
+		}
+		if wrapper.Tenant == "" {
+			continue
+		}
+		if q.tenants[wrapper.Tenant]--; q.tenants[wrapper.Tenant] <= 0 {
+			delete(q.tenants, wrapper.Tenant)
+		}
+	}
+}
+
+// leveled will update the number of items with the given priority
+func (q *queueFinite) leveled(priority, n int) {
+	if q.levels[priority] += n; q.levels[priority] <= 0 {
+		delete(q.levels, priority)
+	}
+}
+
+// watermark will signal if the number of items has crossed the high
+// or low watermark
+func (q *queueFinite) watermark() {
+	if q.watermarks.High <= 0 {
+		return
+	}
+	switch length := len(q.data); {
+	case !q.high && length >= q.watermarks.High:
+		q.high = true
+		internal.SendSignal(q.signalHigh)
+		if q.watermarks.OnHigh != nil {
+			q.watermarks.OnHigh(length)
+		}
+	case q.high && length <= q.watermarks.Low:
+		q.high = false
+		internal.SendSignal(q.signalLow)
+		if q.watermarks.OnLow != nil {
+			q.watermarks.OnLow(length)
+		}
+	}
+}
+
+func (q *queueFinite) enqueued(wrapper *priorityqueue.Wrapper) {
+	q.stats.Enqueued++
+	q.leveled(wrapper.Priority, 1)
+	if len(wrapper.Key) > 0 {
+		q.keyed++
+	}
+	if wrapper.Tenant != "" {
+		q.tenants[wrapper.Tenant]++
+	}
+	if q.observer != nil {
+		q.observer.Enqueued(wrapper)
+	}
+	q.watermark()
+}
+
+func (q *queueFinite) dequeued(wrappers ...*priorityqueue.Wrapper) {
+	q.stats.Dequeued += uint64(len(wrappers))
+	q.removed(wrappers...)
+	if q.observer != nil {
+		for _, wrapper := range wrappers {
+			q.observer.Dequeued(wrapper)
+		}
+	}
+	q.watermark()
+}
+
+// reprioritized will change the priority of the wrapper
+func (q *queueFinite) reprioritized(wrapper *priorityqueue.Wrapper, priority int) {
+	from := wrapper.Priority
+	wrapper.Priority = priority
+	q.leveled(from, -1)
+	q.leveled(priority, 1)
+	priorityqueue.NotifyReprioritized(q.observer, wrapper, from)
+}
+
+func (q *queueFinite) overflowed(priority int) {
+	q.stats.Overflowed++
+	if q.observer != nil {
+		q.observer.Overflowed(priority)
+	}
+}
+
+func (q *queueFinite) rejected(priority int) {
+	q.stats.Rejected++
+	if observer, ok := q.observer.(priorityqueue.RejectionObserver); ok {
+		observer.Rejected(priority)
+		return
+	}
+	q.overflowed(priority)
+}
+
+func (q *queueFinite) evicted(wrappers ...*priorityqueue.Wrapper) {
+	q.stats.Evicted += uint64(len(wrappers))
+	q.removed(wrappers...)
+	if q.observer != nil {
+		for _, wrapper := range wrappers {
+			q.observer.Evicted(wrapper)
+		}
+	}
+	q.watermark()
+}
+
+// admit will return true if an item with the given priority
+// should be admitted
+func (q *queueFinite) admit(priority int) bool {
+	return q.admission == nil || q.admission(priority, len(q.data), cap(q.data))
+}
+
+// enqueue will enqueue the wrapper, it will return an error if the queue
+// (or the wrapper's tenant) is full or if the wrapper isn't admitted
+func (q *queueFinite) enqueue(wrapper *priorityqueue.Wrapper) error {
+	if q.tenantFull(wrapper.Tenant) {
+		q.overflowed(wrapper.Priority)
+		return ErrOverflow
+	}
+	if !q.admit(wrapper.Priority) {
+		q.rejected(wrapper.Priority)
+		return ErrRejected
+	}
+	if overflow := q.insert(wrapper); overflow {
+		q.overflowed(wrapper.Priority)
+		return ErrOverflow
+	}
+	q.enqueued(wrapper)
+	internal.SendSignal(q.signalIn)
+	return nil
+}
+
+// enqueueMultiple will enqueue the wrappers in order, it will return the
+// items that couldn't be enqueued once the queue (or a tenant) is full or
+// an item isn't admitted
+func (q *queueFinite) enqueueMultiple(wrappers []*priorityqueue.Wrapper) ([]interface{}, bool) {
+	var itemEnqueued, overflow bool
+
+	defer func() {
+		if itemEnqueued {
+			internal.SendSignal(q.signalIn)
+		}
+	}()
+	for i, wrapper := range wrappers {
+		switch {
+		case q.tenantFull(wrapper.Tenant):
+			overflow = true
+		case !q.admit(wrapper.Priority):
+			for _, wrapper := range wrappers[i:] {
+				q.rejected(wrapper.Priority)
+			}
+			return internal.Items(wrappers[i:]), true
+		default:
+			overflow = q.insert(wrapper)
+		}
+		if overflow {
+			for _, wrapper := range wrappers[i:] {
+				q.overflowed(wrapper.Priority)
+			}
+			return internal.Items(wrappers[i:]), overflow
+		}
+		itemEnqueued = true
+		q.enqueued(wrapper)
+	}
+	return nil, false
 }
 
 func (q *queueFinite) enqueueLossy(items []*priorityqueue.Wrapper, itemToEnqueue *priorityqueue.Wrapper) (interface{}, bool) {
 	//KIM: this works off of the idea that the items slice is already
-	// sorted
-	lowestPriority := items[0].Priority
-	if itemToEnqueue.Priority < lowestPriority {
+	// sorted, the item at the tail has the lowest priority and is
+	// discarded if the item being enqueued has a greater or equal priority
+	itemDiscarded := items[len(items)-1]
+	if itemToEnqueue.Priority < itemDiscarded.Priority {
+		q.overflowed(itemToEnqueue.Priority)
 		return nil, true
 	}
-	itemDiscarded := items[0]
-	items[0] = itemToEnqueue
-	sort.Sort(priorityqueue.ByPriority(items))
-	sort.Sort(priorityqueue.ByEnqueuedAt(items))
+	items[len(items)-1] = nil
+	q.data = items[:len(items)-1]
+	q.insert(itemToEnqueue)
+	q.evicted(itemDiscarded)
+	q.enqueued(itemToEnqueue)
 	return itemDiscarded.Item, false
 }
 
@@ -61,7 +605,9 @@ func (q *queueFinite) Close() []interface{} {
 	q.Lock()
 	defer q.Unlock()
 
-	remainingElements, _, _ := internal.DequeueMultiple(len(q.data), q.data)
+	remainingWrappers, _, _ := internal.DequeueMultiple(len(q.data), q.data)
+	remainingElements := internal.Items(remainingWrappers)
+	q.removed(remainingWrappers...)
 	if q.signalIn != nil {
 		select {
 		default:
@@ -76,7 +622,14 @@ func (q *queueFinite) Close() []interface{} {
 		case <-q.signalOut:
 		}
 	}
+	if q.signalHigh != nil {
+		close(q.signalHigh)
+	}
+	if q.signalLow != nil {
+		close(q.signalLow)
+	}
 	q.data, q.signalIn, q.signalOut = nil, nil, nil
+	q.signalHigh, q.signalLow = nil, nil
 	return remainingElements
 }
 
@@ -86,17 +639,28 @@ func (q *queueFinite) GarbageCollect() {
 
 	//create a new slice to hold the data copy the data
 	// from the old slice to the new slice and set the
-	// internal data to be the new slice
-	data := make([]*priorityqueue.Wrapper, 0, cap(q.data))
+	// internal data to be the new slice, the maps used to
+	// count items are rebuilt so they can be collected too
+	data := make([]*priorityqueue.Wrapper, len(q.data), cap(q.data))
 	copy(data, q.data)
 	q.data = data
+	q.levels, q.tenants, q.keyed = make(map[int]int), make(map[string]int), 0
+	for _, wrapper := range q.data {
+		q.leveled(wrapper.Priority, 1)
+		if len(wrapper.Key) > 0 {
+			q.keyed++
+		}
+		if wrapper.Tenant != "" {
+			q.tenants[wrapper.Tenant]++
+		}
+	}
 }
 
 func (q *queueFinite) Resize(newSize int) []interface{} {
 	q.Lock()
 	defer q.Unlock()
 
-	var discardedItems []interface{}
+	var discardedItems []*priorityqueue.Wrapper
 
 	//ensure that no operations occur if the size hasn't changed,
 	// if there's a need to remove items, remove them, then copy the old
@@ -129,7 +693,15 @@ func (q *queueFinite) Resize(newSize int) []interface{} {
 	q.data = data
 	q.signalIn = make(chan struct{}, newSize)
 	q.signalOut = make(chan struct{}, newSize)
-	return discardedItems
+
+	//KIM: the watermarks are validated against the new size using the
+	// watermarks that were provided, so growing the queue restores them
+	if q.watermarks = q.configured.validate(newSize); q.watermarks.High <= 0 {
+		q.high = false
+	}
+	q.evicted(discardedItems...)
+	q.watermark()
+	return internal.Items(discardedItems)
 }
 
 func (q *queueFinite) GetSignalIn() <-chan struct{} {
@@ -146,99 +718,204 @@ func (q *queueFinite) GetSignalOut() <-chan struct{} {
 	return q.signalOut
 }
 
+func (q *queueFinite) GetSignalHigh() <-chan struct{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.signalHigh
+}
+
+func (q *queueFinite) GetSignalLow() <-chan struct{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.signalLow
+}
+
 func (q *queueFinite) Dequeue() (interface{}, bool) {
 	q.Lock()
 	defer q.Unlock()
 
-	var item interface{}
-	var underflow bool
+	q.control()
+	items, underflow := q.dequeue(1)
+	if underflow {
+		return nil, underflow
+	}
+	q.dequeued(items...)
+	internal.SendSignal(q.signalOut)
+	return items[0].Item, false
+}
+
+func (q *queueFinite) DequeueWrapper() (*priorityqueue.Wrapper, bool) {
+	q.Lock()
+	defer q.Unlock()
 
-	item, q.data, underflow = internal.Dequeue(q.data)
+	q.control()
+	wrappers, underflow := q.dequeue(1)
 	if underflow {
 		return nil, underflow
 	}
+	q.dequeued(wrappers...)
 	internal.SendSignal(q.signalOut)
-	return item, false
+	return wrappers[0], false
 }
 
 func (q *queueFinite) DequeueMultiple(n int) []interface{} {
 	q.Lock()
 	defer q.Unlock()
 
-	var items []interface{}
-	var underflow bool
-
-	items, q.data, underflow = internal.DequeueMultiple(n, q.data)
+	q.control()
+	items, underflow := q.dequeue(n)
 	if underflow {
 		return nil
 	}
+	q.dequeued(items...)
 	internal.SendSignal(q.signalOut)
-	return items
+	return internal.Items(items)
 }
 
 func (q *queueFinite) Flush() []interface{} {
 	q.Lock()
 	defer q.Unlock()
 
-	var items []interface{}
-	var underflow bool
+	items, underflow := q.dequeue(cap(q.data))
+	if underflow {
+		return nil
+	}
+	q.dequeued(items...)
+	internal.SendSignal(q.signalOut)
+	return internal.Items(items)
+}
+
+func (q *queueFinite) DequeueTail() (interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
 
-	items, q.data, underflow = internal.DequeueMultiple(cap(q.data), q.data)
+	items, underflow := q.dequeueTail(1)
+	if underflow {
+		return nil, underflow
+	}
+	q.dequeued(items...)
+	internal.SendSignal(q.signalOut)
+	return items[0].Item, false
+}
+
+func (q *queueFinite) DequeueTailMultiple(n int) []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	items, underflow := q.dequeueTail(n)
 	if underflow {
 		return nil
 	}
+	q.dequeued(items...)
 	internal.SendSignal(q.signalOut)
-	return items
+	return internal.Items(items)
+}
+
+func (q *queueFinite) DequeueAtLeast(minPriority int) (interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	items := q.dequeueIf(1, atLeast(minPriority))
+	if len(items) == 0 {
+		return nil, true
+	}
+	q.dequeued(items...)
+	internal.SendSignal(q.signalOut)
+	return items[0].Item, false
+}
+
+func (q *queueFinite) DequeueMultipleAtLeast(n, minPriority int) []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	items := q.dequeueIf(n, atLeast(minPriority))
+	if len(items) == 0 {
+		return nil
+	}
+	q.dequeued(items...)
+	internal.SendSignal(q.signalOut)
+	return internal.Items(items)
+}
+
+func (q *queueFinite) DequeueIf(fn func(item interface{}, priority int) bool) []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	items := q.dequeueIf(len(q.data), fn)
+	if len(items) == 0 {
+		return nil
+	}
+	q.dequeued(items...)
+	internal.SendSignal(q.signalOut)
+	return internal.Items(items)
 }
 
 func (q *queueFinite) Enqueue(item interface{}) bool {
-	return q.PriorityEnqueue(item)
+	return q.PriorityEnqueue(item, priorityqueue.ItemPriority(item, q.priorityFunc))
 }
 
 func (q *queueFinite) EnqueueMultiple(items []interface{}) ([]interface{}, bool) {
-	return q.PriorityEnqueueMultiple(items)
+	return q.PriorityEnqueueMultiple(items, priorityqueue.ItemPriorities(items, q.priorityFunc)...)
 }
 
 func (q *queueFinite) EnqueueLossy(item interface{}) (interface{}, bool) {
-	return q.PriorityEnqueueLossy(item)
+	return q.PriorityEnqueueLossy(item, priorityqueue.ItemPriority(item, q.priorityFunc))
 }
 
 func (q *queueFinite) PriorityEnqueue(item interface{}, priorities ...int) bool {
+	return q.TenantEnqueue("", item, priorities...)
+}
+
+func (q *queueFinite) PriorityEnqueueMultiple(items []interface{}, priorities ...int) ([]interface{}, bool) {
+	return q.TenantEnqueueMultiple("", items, priorities...)
+}
+
+func (q *queueFinite) EnqueueWrapper(wrapper *priorityqueue.Wrapper) bool {
 	q.Lock()
 	defer q.Unlock()
 
-	var overflow bool
+	w := *wrapper
+	w.EnqueuedAt = q.now().UnixNano()
+	return q.enqueue(&w) != nil
+}
+
+func (q *queueFinite) TryEnqueue(item interface{}, priorities ...int) error {
+	q.Lock()
+	defer q.Unlock()
 
 	priority := priorityqueue.DefaultPriority
 	if len(priorities) > 0 {
 		priority = priorities[0]
 	}
-	if q.data, overflow = internal.Enqueue(q.data, &priorityqueue.Wrapper{
+	return q.enqueue(&priorityqueue.Wrapper{
 		Item:       item,
 		Priority:   priority,
-		EnqueuedAt: time.Now().UnixNano(),
-	}); overflow {
-		return true
-	}
-	sort.Sort(priorityqueue.ByPriority(q.data))
-	sort.Sort(priorityqueue.ByEnqueuedAt(q.data))
-	internal.SendSignal(q.signalIn)
-	return false
+		EnqueuedAt: q.now().UnixNano(),
+	})
 }
 
-func (q *queueFinite) PriorityEnqueueMultiple(items []interface{}, priorities ...int) ([]interface{}, bool) {
+func (q *queueFinite) TenantEnqueue(tenant string, item interface{}, priorities ...int) bool {
 	q.Lock()
 	defer q.Unlock()
 
-	var itemEnqueued, overflow bool
+	priority := priorityqueue.DefaultPriority
+	if len(priorities) > 0 {
+		priority = priorities[0]
+	}
+	return q.enqueue(&priorityqueue.Wrapper{
+		Item:       item,
+		Priority:   priority,
+		EnqueuedAt: q.now().UnixNano(),
+		Tenant:     tenant,
+	}) != nil
+}
+
+func (q *queueFinite) TenantEnqueueMultiple(tenant string, items []interface{}, priorities ...int) ([]interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
 
-	defer func() {
-		if itemEnqueued {
-			sort.Sort(priorityqueue.ByPriority(q.data))
-			sort.Sort(priorityqueue.ByEnqueuedAt(q.data))
-			internal.SendSignal(q.signalIn)
-		}
-	}()
 	if len(priorities) != len(items) {
 		priority := priorityqueue.DefaultPriority
 		if len(priorities) > 0 {
@@ -249,35 +926,79 @@ func (q *queueFinite) PriorityEnqueueMultiple(items []interface{}, priorities ..
 			priorities = append(priorities, priority)
 		}
 	}
+	wrappers := make([]*priorityqueue.Wrapper, 0, len(items))
 	for i, item := range items {
-		q.data, overflow = internal.Enqueue(q.data, &priorityqueue.Wrapper{
+		wrappers = append(wrappers, &priorityqueue.Wrapper{
 			Item:       item,
 			Priority:   priorities[i],
-			EnqueuedAt: time.Now().UnixNano(),
+			EnqueuedAt: q.now().UnixNano(),
+			Tenant:     tenant,
 		})
-		if overflow {
-			return items[i:], overflow
+	}
+	return q.enqueueMultiple(wrappers)
+}
+
+func (q *queueFinite) KeyEnqueue(item interface{}, key ...int) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	priority, levels := priorityqueue.SplitKey(key)
+	return q.enqueue(&priorityqueue.Wrapper{
+		Item:       item,
+		Priority:   priority,
+		Key:        levels,
+		EnqueuedAt: q.now().UnixNano(),
+	}) != nil
+}
+
+func (q *queueFinite) KeyEnqueueMultiple(items []interface{}, keys ...[]int) ([]interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(keys) != len(items) {
+		var key []int
+
+		if len(keys) > 0 {
+			key = keys[0]
+		}
+		keys = make([][]int, 0, len(items))
+		for range items {
+			keys = append(keys, key)
 		}
 	}
-	return nil, false
+	wrappers := make([]*priorityqueue.Wrapper, 0, len(items))
+	for i, item := range items {
+		priority, levels := priorityqueue.SplitKey(keys[i])
+		wrappers = append(wrappers, &priorityqueue.Wrapper{
+			Item:       item,
+			Priority:   priority,
+			Key:        levels,
+			EnqueuedAt: q.now().UnixNano(),
+		})
+	}
+	return q.enqueueMultiple(wrappers)
 }
 
 func (q *queueFinite) PriorityEnqueueLossy(item interface{}, priorities ...int) (interface{}, bool) {
 	q.Lock()
 	defer q.Unlock()
 
-	var overflow bool
-
 	priority := priorityqueue.DefaultPriority
 	if len(priorities) > 0 {
 		priority = priorities[0]
 	}
+	if !q.admit(priority) {
+		q.rejected(priority)
+		return nil, true
+	}
 	wrappedItem := &priorityqueue.Wrapper{
 		Item:       item,
 		Priority:   priority,
-		EnqueuedAt: time.Now().UnixNano(),
+		EnqueuedAt: q.now().UnixNano(),
 	}
-	if q.data, overflow = internal.Enqueue(q.data, wrappedItem); !overflow {
+	if overflow := q.insert(wrappedItem); !overflow {
+		q.enqueued(wrappedItem)
+		internal.SendSignal(q.signalIn)
 		return nil, false
 	}
 	return q.enqueueLossy(q.data, wrappedItem)
@@ -302,8 +1023,8 @@ func (q *queueFinite) Peek() []interface{} {
 	defer q.RUnlock()
 
 	items := make([]interface{}, 0, len(q.data))
-	for i := 0; i < len(q.data); i++ {
-		items = append(items, q.data[i].Item)
+	for _, wrapper := range q.peek(len(q.data)) {
+		items = append(items, wrapper.Item)
 	}
 	return items
 }
@@ -315,7 +1036,7 @@ func (q *queueFinite) PeekHead() (item interface{}, underflow bool) {
 	if len(q.data) <= 0 {
 		return nil, true
 	}
-	return q.data[0].Item, false
+	return q.peek(1)[0].Item, false
 }
 
 func (q *queueFinite) PeekFromHead(n int) []interface{} {
@@ -325,12 +1046,203 @@ func (q *queueFinite) PeekFromHead(n int) []interface{} {
 	if len(q.data) == 0 {
 		return nil
 	}
-	if n > len(q.data) {
-		n = len(q.data)
-	}
-	items := make([]interface{}, 0, n)
-	for i := 0; i < n; i++ {
-		items = append(items, q.data[i].Item)
+	wrappers := q.peek(n)
+	items := make([]interface{}, 0, len(wrappers))
+	for _, wrapper := range wrappers {
+		items = append(items, wrapper.Item)
 	}
 	return items
 }
+
+func (q *queueFinite) PeekTail() (interface{}, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if len(q.data) <= 0 {
+		return nil, true
+	}
+	return q.data[len(q.data)-1].Item, false
+}
+
+func (q *queueFinite) PeekFromTail(n int) []interface{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	if len(q.data) == 0 {
+		return nil
+	}
+	return internal.Items(q.peekTail(n))
+}
+
+func (q *queueFinite) PeekAtLeast(minPriority int) (interface{}, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	wrappers := q.peekIf(1, atLeast(minPriority))
+	if len(wrappers) == 0 {
+		return nil, true
+	}
+	return wrappers[0].Item, false
+}
+
+func (q *queueFinite) PeekMultipleAtLeast(n, minPriority int) []interface{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return internal.Items(q.peekIf(n, atLeast(minPriority)))
+}
+
+func (q *queueFinite) PeekIf(fn func(item interface{}, priority int) bool) []interface{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return internal.Items(q.peekIf(len(q.data), fn))
+}
+
+func (q *queueFinite) PeekWrappers() []*priorityqueue.Wrapper {
+	q.RLock()
+	defer q.RUnlock()
+
+	wrappers := make([]*priorityqueue.Wrapper, 0, len(q.data))
+	for _, wrapper := range q.peek(len(q.data)) {
+		w := *wrapper
+		wrappers = append(wrappers, &w)
+	}
+	return wrappers
+}
+
+func (q *queueFinite) PeekWrappersFromHead(n int) []*priorityqueue.Wrapper {
+	q.RLock()
+	defer q.RUnlock()
+
+	if n < 0 {
+		n = 0
+	}
+	wrappers := make([]*priorityqueue.Wrapper, 0, n)
+	for _, wrapper := range q.peek(n) {
+		w := *wrapper
+		wrappers = append(wrappers, &w)
+	}
+	return wrappers
+}
+
+func (q *queueFinite) PeekHeadWrapper() (*priorityqueue.Wrapper, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if len(q.data) <= 0 {
+		return nil, true
+	}
+	wrapper := *q.peek(1)[0]
+	return &wrapper, false
+}
+
+func (q *queueFinite) Reprioritize(from, to int) int {
+	q.Lock()
+	defer q.Unlock()
+
+	var n int
+
+	if from == to {
+		return 0
+	}
+	for _, wrapper := range q.data {
+		if wrapper.Priority == from {
+			q.reprioritized(wrapper, to)
+			n++
+		}
+	}
+	if n > 0 {
+		q.sort()
+	}
+	return n
+}
+
+func (q *queueFinite) Rescore() int {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.rescore()
+}
+
+func (q *queueFinite) Snapshot(w io.Writer) error {
+	q.RLock()
+	defer q.RUnlock()
+
+	return priorityqueue.WriteSnapshot(w, q.data)
+}
+
+func (q *queueFinite) Restore(r io.Reader) error {
+	wrappers, err := priorityqueue.ReadSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if len(wrappers) > cap(q.data) {
+		return priorityqueue.ErrSnapshotOverflow
+	}
+	evictedItems := q.data
+	q.data = append(make([]*priorityqueue.Wrapper, 0, cap(q.data)), wrappers...)
+	q.sort()
+	q.evicted(evictedItems...)
+	for _, wrapper := range q.data {
+		q.enqueued(wrapper)
+	}
+	if len(q.data) > 0 {
+		internal.SendSignal(q.signalIn)
+	}
+	return nil
+}
+
+func (q *queueFinite) LengthByPriority() map[int]int {
+	q.RLock()
+	defer q.RUnlock()
+
+	lengths := make(map[int]int, len(q.levels))
+	for priority, n := range q.levels {
+		lengths[priority] = n
+	}
+	return lengths
+}
+
+func (q *queueFinite) OldestByPriority() map[int]time.Time {
+	q.RLock()
+	defer q.RUnlock()
+
+	oldest := make(map[int]time.Time, len(q.levels))
+	for priority := range q.levels {
+		//KIM: items are sorted by priority, so the items for each priority
+		// can be found using a binary search; the first item is the oldest
+		// unless items have a key
+		i := sort.Search(len(q.data), func(i int) bool {
+			return q.data[i].Priority <= priority
+		})
+		if i >= len(q.data) || q.data[i].Priority != priority {
+			continue
+		}
+		enqueuedAt := q.data[i].EnqueuedAt
+		for j := i + 1; q.keyed > 0 && j < len(q.data) && q.data[j].Priority == priority; j++ {
+			if q.data[j].EnqueuedAt < enqueuedAt {
+				enqueuedAt = q.data[j].EnqueuedAt
+			}
+		}
+		oldest[priority] = time.Unix(0, enqueuedAt)
+	}
+	return oldest
+}
+
+func (q *queueFinite) Stats() Stats {
+	q.RLock()
+	defer q.RUnlock()
+
+	stats := q.stats
+	stats.Depth = len(q.data)
+	if len(q.data) > 0 {
+		stats.Highest = q.data[0].Priority
+		stats.Lowest = q.data[len(q.data)-1].Priority
+	}
+	return stats
+}
@@ -1,17 +1,22 @@
 package priorityfinite_test
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 	"time"
 
 	goqueue "github.com/antonio-alexander/go-queue"
 	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
 	goqueuepriorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	metrics "github.com/antonio-alexander/go-queue-priority/metrics"
 	finite "github.com/antonio-alexander/go-queue/finite"
 
 	goqueuepriorityfinite_tests "github.com/antonio-alexander/go-queue-priority/finite/tests"
 	finite_tests "github.com/antonio-alexander/go-queue/finite/tests"
 	goqueue_tests "github.com/antonio-alexander/go-queue/tests"
+
+	"github.com/stretchr/testify/assert"
 )
 
 const (
@@ -179,4 +184,806 @@ func TestPriorityFiniteQueue(t *testing.T) {
 	} {
 		return goqueuepriorityfinite.New(size)
 	}))
+	t.Run("Test Snapshot", goqueuepriorityfinite_tests.TestSnapshot(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueuepriority.PriorityEnqueuer
+		goqueuepriority.WrapperPeeker
+		goqueuepriority.Snapshotter
+	} {
+		return goqueuepriorityfinite.New(size)
+	}))
+}
+
+// TestWeightedFairQueueing is meant to simulate a consumer of a queue with
+// weights where each priority always has items available, it will confirm
+// that the ratio of dequeues for each priority converges to its weight
+// and that priorities that are empty are skipped
+func TestWeightedFairQueueing(t *testing.T) {
+	const dequeues int = 6000
+
+	weights := goqueuepriorityfinite.Weights{3: 3, 2: 2, 1: 1}
+	q := goqueuepriorityfinite.New(30, weights)
+	defer q.Close()
+	for priority := range weights {
+		for i := 0; i < 10; i++ {
+			assert.False(t, q.PriorityEnqueue(priority, priority))
+		}
+	}
+
+	//dequeue an item and re-enqueue an item with the same priority
+	// so that every priority remains backlogged
+	counts := make(map[int]int)
+	for i := 0; i < dequeues; i++ {
+		item, underflow := q.Dequeue()
+		assert.False(t, underflow)
+		priority := item.(int)
+		counts[priority]++
+		assert.False(t, q.PriorityEnqueue(priority, priority))
+	}
+	for priority, weight := range weights {
+		expected := float64(dequeues) * float64(weight) / 6
+		assert.InDelta(t, expected, float64(counts[priority]), expected*0.01)
+	}
+
+	//peek should match the order items are dequeued
+	items := q.Peek()
+	assert.Equal(t, items[:6], q.DequeueMultiple(6))
+
+	//once the other priorities are empty, the remaining priority
+	// is dequeued
+	q.Flush()
+	assert.False(t, q.PriorityEnqueue(1, 1))
+	assert.False(t, q.PriorityEnqueue(2, 1))
+	assert.Equal(t, []interface{}{1, 2}, q.Flush())
+}
+
+// TestTenantFairness is meant to confirm that items with the same priority
+// are dequeued in round robin between tenants and that a single tenant
+// can't exceed its capacity
+func TestTenantFairness(t *testing.T) {
+	q := goqueuepriorityfinite.New(10, goqueuepriorityfinite.TenantCapacity(4))
+	defer q.Close()
+
+	//the noisy tenant should overflow once it reaches its capacity, but
+	// should not prevent other tenants from enqueueing
+	itemsRemaining, overflow := q.TenantEnqueueMultiple("noisy", []interface{}{
+		"noisy1", "noisy2", "noisy3", "noisy4", "noisy5",
+	})
+	assert.True(t, overflow)
+	assert.Equal(t, []interface{}{"noisy5"}, itemsRemaining)
+	assert.True(t, q.TenantEnqueue("noisy", "noisy5"))
+	assert.False(t, q.TenantEnqueue("quiet", "quiet1"))
+	assert.False(t, q.TenantEnqueue("quiet", "quiet2"))
+	assert.False(t, q.PriorityEnqueue("anonymous1"))
+	assert.False(t, q.TenantEnqueue("quiet", "urgent", 1))
+	expected := []interface{}{
+		"urgent",
+		"anonymous1",
+		"noisy1",
+		"quiet1",
+		"noisy2",
+		"quiet2",
+		"noisy3",
+		"noisy4",
+	}
+	assert.Equal(t, expected, q.Peek())
+	item, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, expected[0], item)
+	assert.Equal(t, expected[1:4], q.DequeueMultiple(3))
+
+	//once items have been dequeued, the tenant should be able to enqueue
+	assert.False(t, q.TenantEnqueue("noisy", "noisy5"))
+	assert.Equal(t, []interface{}{"noisy2", "quiet2", "noisy3", "noisy4", "noisy5"}, q.Flush())
+}
+
+// TestReprioritize is meant to confirm that items can be moved between
+// priorities and that they're ordered by age within their new priority
+func TestReprioritize(t *testing.T) {
+	q := goqueuepriorityfinite.New(5)
+	defer q.Close()
+
+	assert.False(t, q.PriorityEnqueue("old", 1))
+	assert.False(t, q.PriorityEnqueue("urgent", 2))
+	assert.False(t, q.PriorityEnqueue("newer", 1))
+	assert.False(t, q.PriorityEnqueue("newest", 2))
+	assert.False(t, q.PriorityEnqueue("ignored", 0))
+	assert.Equal(t, 0, q.Reprioritize(3, 4))
+	assert.Equal(t, 0, q.Reprioritize(1, 1))
+	assert.Equal(t, 2, q.Reprioritize(1, 2))
+	assert.Equal(t, []interface{}{"old", "urgent", "newer", "newest", "ignored"}, q.Peek())
+	wrapper, underflow := q.PeekHeadWrapper()
+	assert.False(t, underflow)
+	assert.Equal(t, 2, wrapper.Priority)
+}
+
+// TestReprioritizeMetrics is meant to confirm that the depth of each priority
+// is maintained when items are reprioritized, whether or not the observer
+// implements ReprioritizationObserver
+func TestReprioritizeMetrics(t *testing.T) {
+	for cDesc, observer := range map[string]func(metrics.Collector) goqueuepriority.Observer{
+		"reprioritization_observer": func(c metrics.Collector) goqueuepriority.Observer { return c },
+		"observer":                  func(c metrics.Collector) goqueuepriority.Observer { return &rejections{Observer: c} },
+	} {
+		t.Run(cDesc, func(t *testing.T) {
+			collector := metrics.New()
+			q := goqueuepriorityfinite.New(3, observer(collector))
+			defer q.Close()
+
+			assert.False(t, q.PriorityEnqueue("a", 1))
+			assert.Equal(t, 1, q.Reprioritize(1, 5))
+			for _, m := range collector.Snapshot() {
+				assert.Equal(t, map[int]int64{1: 0, 5: 1}[m.Priority], m.Depth, m.Priority)
+			}
+			_, underflow := q.Dequeue()
+			assert.False(t, underflow)
+			for _, m := range collector.Snapshot() {
+				assert.Equal(t, int64(0), m.Depth, m.Priority)
+			}
+		})
+	}
+}
+
+type job struct {
+	name     string
+	priority int
+}
+
+func (j job) Priority() int {
+	return j.priority
+}
+
+// TestPrioritizer is meant to confirm that items enqueued without a priority
+// provide their own priority and that a priority function takes precedence
+func TestPrioritizer(t *testing.T) {
+	q := goqueuepriorityfinite.New(5)
+	defer q.Close()
+
+	assert.False(t, q.Enqueue(job{name: "low", priority: 1}))
+	assert.False(t, q.Enqueue("default"))
+	remaining, overflow := q.EnqueueMultiple([]interface{}{job{name: "high", priority: 3}, job{name: "medium", priority: 2}})
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	assert.Equal(t, []interface{}{
+		job{name: "high", priority: 3},
+		job{name: "medium", priority: 2},
+		job{name: "low", priority: 1},
+		"default",
+	}, q.Flush())
+
+	q = goqueuepriorityfinite.New(3, goqueuepriority.PriorityFunc(func(item interface{}) int {
+		if s, ok := item.(string); ok {
+			return len(s)
+		}
+		return goqueuepriority.DefaultPriority
+	}))
+	defer q.Close()
+
+	assert.False(t, q.Enqueue("a"))
+	assert.False(t, q.Enqueue(job{name: "ignored", priority: 5}))
+	assert.False(t, q.Enqueue("abc"))
+	assert.Equal(t, []interface{}{"abc", "a", job{name: "ignored", priority: 5}}, q.Flush())
+}
+
+// TestDynamicPriority is meant to confirm that items are rescored before
+// they're dequeued, no more often than the interval, and that items with the
+// same score are dequeued in the order they were enqueued
+func TestDynamicPriority(t *testing.T) {
+	thresholds := map[string]int{"a": 0, "b": 0, "c": 0}
+	score := func(item interface{}) int {
+		return thresholds[item.(string)]
+	}
+
+	q := goqueuepriorityfinite.New(5, goqueuepriorityfinite.DynamicPriority{Score: score})
+	defer q.Close()
+
+	assert.False(t, q.Enqueue("a"))
+	assert.False(t, q.Enqueue("b"))
+	assert.False(t, q.Enqueue("c"))
+	thresholds["c"] = 2
+	item, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, "c", item)
+	thresholds["b"] = 1
+	assert.Equal(t, 1, q.Rescore())
+	assert.Equal(t, []interface{}{"b", "a"}, q.Peek())
+	assert.Equal(t, 0, q.Rescore())
+
+	q = goqueuepriorityfinite.New(5, goqueuepriorityfinite.DynamicPriority{
+		Score:    score,
+		Interval: time.Hour,
+	})
+	defer q.Close()
+
+	thresholds["b"] = 0
+	assert.False(t, q.Enqueue("a"))
+	assert.False(t, q.Enqueue("b"))
+	assert.Equal(t, 0, q.Rescore())
+	thresholds["b"] = 1
+	assert.Equal(t, 0, q.Rescore())
+	assert.Equal(t, []interface{}{"a", "b"}, q.DequeueMultiple(2))
+}
+
+// TestDynamicPriorityMetrics is meant to confirm that the depth of each
+// priority is maintained when items are rescored
+func TestDynamicPriorityMetrics(t *testing.T) {
+	priority := 1
+	collector := metrics.New()
+	q := goqueuepriorityfinite.New(3, collector, goqueuepriorityfinite.DynamicPriority{
+		Score: func(interface{}) int { return priority },
+	})
+	defer q.Close()
+
+	assert.False(t, q.PriorityEnqueue("a", 1))
+	priority = 5
+	assert.Equal(t, 1, q.Rescore())
+	for _, m := range collector.Snapshot() {
+		assert.Equal(t, map[int]int64{1: 0, 5: 1}[m.Priority], m.Depth, m.Priority)
+	}
+	assert.Equal(t, map[int]int{5: 1}, q.LengthByPriority())
+	_, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	for _, m := range collector.Snapshot() {
+		assert.Equal(t, int64(0), m.Depth, m.Priority)
+	}
+}
+
+// TestKeyEnqueue is meant to confirm that items are dequeued by their
+// composite key and that a single element key is the same as a priority
+func TestKeyEnqueue(t *testing.T) {
+	q := goqueuepriorityfinite.New(6)
+	defer q.Close()
+
+	assert.False(t, q.KeyEnqueue("sev1-tier1", 1, 1))
+	assert.False(t, q.PriorityEnqueue("sev1", 1))
+	assert.False(t, q.KeyEnqueue("sev2", 2))
+	remaining, overflow := q.KeyEnqueueMultiple([]interface{}{"sev1-tier2-late", "sev1-tier2-early"},
+		[]int{1, 2, -20}, []int{1, 2, -10})
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	assert.False(t, q.KeyEnqueue("default"))
+	assert.Equal(t, []interface{}{
+		"sev2",
+		"sev1-tier2-early",
+		"sev1-tier2-late",
+		"sev1-tier1",
+		"sev1",
+		"default",
+	}, q.Peek())
+	wrapper, underflow := q.PeekHeadWrapper()
+	assert.False(t, underflow)
+	assert.Equal(t, 2, wrapper.Priority)
+	assert.Nil(t, wrapper.Key)
+	assert.Equal(t, []interface{}{"sev2", "sev1-tier2-early"}, q.DequeueMultiple(2))
+
+	//validate that keys are retained by snapshots
+	buffer := &bytes.Buffer{}
+	assert.Nil(t, q.Snapshot(buffer))
+	restored := goqueuepriorityfinite.New(6)
+	defer restored.Close()
+	assert.Nil(t, restored.Restore(buffer))
+	assert.Equal(t, q.Peek(), restored.Peek())
+	remaining, overflow = q.KeyEnqueueMultiple([]interface{}{"a", "b", "c"}, []int{3})
+	assert.True(t, overflow)
+	assert.Equal(t, []interface{}{"c"}, remaining)
+	assert.Equal(t, []interface{}{"a", "b"}, q.PeekFromHead(2))
+}
+
+// TestTail is meant to confirm that items can be peeked and dequeued from the
+// tail, starting with the newest item with the lowest priority
+func TestTail(t *testing.T) {
+	q := goqueuepriorityfinite.New(5)
+	defer q.Close()
+
+	item, underflow := q.PeekTail()
+	assert.True(t, underflow)
+	assert.Nil(t, item)
+	_, underflow = q.DequeueTail()
+	assert.True(t, underflow)
+	assert.Nil(t, q.DequeueTailMultiple(1))
+	assert.Nil(t, q.PeekFromTail(1))
+
+	assert.False(t, q.PriorityEnqueue("low_old", 0))
+	assert.False(t, q.PriorityEnqueue("high", 2))
+	assert.False(t, q.PriorityEnqueue("low_new", 0))
+	assert.False(t, q.PriorityEnqueue("medium", 1))
+	item, underflow = q.PeekTail()
+	assert.False(t, underflow)
+	assert.Equal(t, "low_new", item)
+	assert.Equal(t, []interface{}{"low_new", "low_old", "medium"}, q.PeekFromTail(3))
+	assert.Equal(t, []interface{}{"low_new", "low_old", "medium", "high"}, q.PeekFromTail(10))
+	item, underflow = q.DequeueTail()
+	assert.False(t, underflow)
+	assert.Equal(t, "low_new", item)
+	assert.Equal(t, []interface{}{"low_old", "medium"}, q.DequeueTailMultiple(2))
+	assert.Equal(t, 1, q.Length())
+	item, underflow = q.PeekHead()
+	assert.False(t, underflow)
+	assert.Equal(t, "high", item)
+}
+
+// TestConditionalDequeue is meant to confirm that only items that match are
+// peeked and dequeued and that the order of the remaining items is preserved
+func TestConditionalDequeue(t *testing.T) {
+	q := goqueuepriorityfinite.New(6)
+	defer q.Close()
+
+	assert.False(t, q.PriorityEnqueue("low1", 0))
+	assert.False(t, q.PriorityEnqueue("urgent1", 5))
+	assert.False(t, q.PriorityEnqueue("medium", 2))
+	assert.False(t, q.PriorityEnqueue("urgent2", 5))
+	assert.False(t, q.PriorityEnqueue("high", 3))
+	assert.False(t, q.PriorityEnqueue("low2", 0))
+
+	_, underflow := q.PeekAtLeast(6)
+	assert.True(t, underflow)
+	_, underflow = q.DequeueAtLeast(6)
+	assert.True(t, underflow)
+	assert.Nil(t, q.DequeueMultipleAtLeast(2, 6))
+	item, underflow := q.PeekAtLeast(3)
+	assert.False(t, underflow)
+	assert.Equal(t, "urgent1", item)
+	assert.Equal(t, []interface{}{"urgent1", "urgent2", "high"}, q.PeekMultipleAtLeast(5, 3))
+	item, underflow = q.DequeueAtLeast(5)
+	assert.False(t, underflow)
+	assert.Equal(t, "urgent1", item)
+	assert.Equal(t, []interface{}{"urgent2", "high"}, q.DequeueMultipleAtLeast(5, 3))
+
+	isLow := func(item interface{}, priority int) bool {
+		return strings.HasPrefix(item.(string), "low")
+	}
+	assert.Equal(t, []interface{}{"low1", "low2"}, q.PeekIf(isLow))
+	assert.Equal(t, 3, q.Length())
+	assert.Equal(t, []interface{}{"low1", "low2"}, q.DequeueIf(isLow))
+	assert.Nil(t, q.DequeueIf(isLow))
+	assert.Equal(t, []interface{}{"medium"}, q.Flush())
+}
+
+// TestStatistics is meant to confirm that the length and oldest item of each
+// priority and the stats of the queue are maintained as items move through it
+func TestStatistics(t *testing.T) {
+	q := goqueuepriorityfinite.New(4)
+	defer q.Close()
+
+	assert.Equal(t, goqueuepriorityfinite.Stats{}, q.Stats())
+	assert.Empty(t, q.LengthByPriority())
+	assert.Empty(t, q.OldestByPriority())
+
+	start := time.Now()
+	assert.False(t, q.PriorityEnqueue("low_old", 0))
+	time.Sleep(time.Millisecond)
+	assert.False(t, q.KeyEnqueue("low_keyed", 0, 5))
+	assert.False(t, q.PriorityEnqueue("high", 2))
+	assert.False(t, q.PriorityEnqueue("medium", 1))
+	assert.True(t, q.PriorityEnqueue("overflow", 1))
+	assert.Equal(t, map[int]int{0: 2, 1: 1, 2: 1}, q.LengthByPriority())
+	oldest := q.OldestByPriority()
+	assert.Len(t, oldest, 3)
+	assert.False(t, oldest[0].Before(start))
+	assert.True(t, oldest[0].Before(oldest[1]))
+	assert.True(t, oldest[2].Before(oldest[1]))
+	wrappers := q.PeekWrappers()
+	assert.Equal(t, wrappers[3].EnqueuedAt, oldest[0].UnixNano())
+	assert.Equal(t, goqueuepriorityfinite.Stats{
+		Depth:      4,
+		Enqueued:   4,
+		Overflowed: 1,
+		Highest:    2,
+		Lowest:     0,
+	}, q.Stats())
+
+	item, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, "high", item)
+	assert.Equal(t, 1, q.Reprioritize(1, 3))
+	assert.Equal(t, map[int]int{0: 2, 3: 1}, q.LengthByPriority())
+	assert.Equal(t, 3, q.Stats().Highest)
+	assert.Equal(t, []interface{}{"medium"}, q.Resize(2))
+	assert.Equal(t, map[int]int{0: 2}, q.LengthByPriority())
+	assert.Equal(t, goqueuepriorityfinite.Stats{
+		Depth:      2,
+		Enqueued:   4,
+		Dequeued:   1,
+		Overflowed: 1,
+		Evicted:    1,
+		Highest:    0,
+		Lowest:     0,
+	}, q.Stats())
+}
+
+// TestWatermarks is meant to confirm that the high watermark is signaled once
+// the length reaches it and the low watermark is signaled once the length
+// drops to it afterwards
+func TestWatermarks(t *testing.T) {
+	var highs, lows []int
+
+	q := goqueuepriorityfinite.New(5, goqueuepriorityfinite.Watermarks{
+		High:   4,
+		Low:    1,
+		OnHigh: func(length int) { highs = append(highs, length) },
+		OnLow:  func(length int) { lows = append(lows, length) },
+	})
+	defer q.Close()
+
+	signalHigh, signalLow := q.GetSignalHigh(), q.GetSignalLow()
+	remaining, overflow := q.EnqueueMultiple([]interface{}{1, 2, 3})
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	assert.Empty(t, highs)
+	assert.False(t, q.Enqueue(4))
+	assert.False(t, q.Enqueue(5))
+	assert.Equal(t, []int{4}, highs)
+	select {
+	default:
+		assert.Fail(t, "expected high watermark signal")
+	case <-signalHigh:
+	}
+
+	//the low watermark should only be signaled once the length drops to it
+	assert.Len(t, q.DequeueMultiple(3), 3)
+	assert.Empty(t, lows)
+	_, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, []int{1}, lows)
+	select {
+	default:
+		assert.Fail(t, "expected low watermark signal")
+	case <-signalLow:
+	}
+	_, underflow = q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, []int{1}, lows)
+
+	//once the low watermark is crossed, the high watermark can be crossed again
+	remaining, overflow = q.EnqueueMultiple([]interface{}{1, 2, 3, 4})
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	assert.Equal(t, []int{4, 4}, highs)
+	select {
+	default:
+	case <-signalLow:
+		assert.Fail(t, "unexpected low watermark signal")
+	}
+
+	//validate that a high watermark greater than the size is reduced to
+	// the size and that watermarks where the low watermark isn't less
+	// than the high watermark are ignored
+	highs, lows = nil, nil
+	q = goqueuepriorityfinite.New(2, goqueuepriorityfinite.Watermarks{
+		High:   10,
+		Low:    -1,
+		OnHigh: func(length int) { highs = append(highs, length) },
+		OnLow:  func(length int) { lows = append(lows, length) },
+	})
+	defer q.Close()
+
+	remaining, overflow = q.EnqueueMultiple([]interface{}{1, 2})
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	assert.Equal(t, []int{2}, highs)
+	assert.Len(t, q.Flush(), 2)
+	assert.Equal(t, []int{0}, lows)
+	highs, lows = nil, nil
+	q = goqueuepriorityfinite.New(5, goqueuepriorityfinite.Watermarks{
+		High:   2,
+		Low:    3,
+		OnHigh: func(length int) { highs = append(highs, length) },
+		OnLow:  func(length int) { lows = append(lows, length) },
+	})
+	defer q.Close()
+
+	remaining, overflow = q.EnqueueMultiple([]interface{}{1, 2, 3, 4})
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	assert.Len(t, q.Flush(), 4)
+	assert.Empty(t, highs)
+	assert.Empty(t, lows)
+
+	//validate that the watermarks are validated again when the queue is
+	// resized; the high watermark is reduced to the new size and restored
+	// once the queue grows
+	highs, lows = nil, nil
+	q = goqueuepriorityfinite.New(5, goqueuepriorityfinite.Watermarks{
+		High:   4,
+		Low:    1,
+		OnHigh: func(length int) { highs = append(highs, length) },
+		OnLow:  func(length int) { lows = append(lows, length) },
+	})
+	defer q.Close()
+
+	remaining, overflow = q.EnqueueMultiple([]interface{}{1, 2})
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	assert.Empty(t, q.Resize(2))
+	assert.Equal(t, []int{2}, highs)
+	assert.Empty(t, q.Resize(5))
+	remaining, overflow = q.EnqueueMultiple([]interface{}{3, 4})
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	assert.Equal(t, []int{2}, highs)
+	assert.Len(t, q.DequeueMultiple(3), 3)
+	assert.Equal(t, []int{1}, lows)
+	remaining, overflow = q.EnqueueMultiple([]interface{}{5, 6})
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	assert.Equal(t, []int{2}, highs)
+	assert.False(t, q.Enqueue(7))
+	assert.Equal(t, []int{2, 4}, highs)
+}
+
+type rejections struct {
+	goqueuepriority.Observer
+	overflowed []int
+	rejected   []int
+}
+
+func (r *rejections) Overflowed(priority int) {
+	r.overflowed = append(r.overflowed, priority)
+}
+
+func (r *rejections) Rejected(priority int) {
+	r.rejected = append(r.rejected, priority)
+}
+
+// TestAdmission is meant to confirm that items with a low priority are
+// rejected as the queue fills up and that rejections are reported
+// separately from overflows
+func TestAdmission(t *testing.T) {
+	observer := &rejections{Observer: metrics.New()}
+	q := goqueuepriorityfinite.New(5, observer, goqueuepriorityfinite.AdmissionSteps{
+		{Fill: 0.8, MinPriority: 10},
+		{Fill: 0.4, MinPriority: 1},
+	})
+	defer q.Close()
+
+	assert.Nil(t, q.TryEnqueue("a", 0))
+	assert.Nil(t, q.TryEnqueue("b", 0))
+	assert.ErrorIs(t, q.TryEnqueue("c", 0), goqueuepriorityfinite.ErrRejected)
+	assert.True(t, q.Enqueue("c"))
+	remaining, overflow := q.PriorityEnqueueMultiple([]interface{}{"c", "d"}, 1, 0)
+	assert.True(t, overflow)
+	assert.Equal(t, []interface{}{"d"}, remaining)
+	assert.Nil(t, q.TryEnqueue("e", 1))
+	item, overflow := q.PriorityEnqueueLossy("f", 5)
+	assert.True(t, overflow)
+	assert.Nil(t, item)
+	assert.Nil(t, q.TryEnqueue("g", 10))
+	assert.ErrorIs(t, q.TryEnqueue("h", 10), goqueuepriorityfinite.ErrOverflow)
+	assert.Equal(t, []int{0, 0, 0, 5}, observer.rejected)
+	assert.Equal(t, []int{10}, observer.overflowed)
+	assert.Equal(t, uint64(4), q.Stats().Rejected)
+	assert.Equal(t, uint64(1), q.Stats().Overflowed)
+	assert.Equal(t, []interface{}{"g", "c", "e", "a", "b"}, q.Flush())
+
+	//validate that an admission function can be used
+	q = goqueuepriorityfinite.New(2, goqueuepriorityfinite.AdmissionFunc(func(priority, length, capacity int) bool {
+		return length == 0 || priority > 0
+	}))
+	defer q.Close()
+
+	assert.Nil(t, q.TryEnqueue("a", 0))
+	assert.ErrorIs(t, q.TryEnqueue("b", 0), goqueuepriorityfinite.ErrRejected)
+	assert.Nil(t, q.TryEnqueue("b", 1))
+
+	//validate that the tenant capacity is checked before admission when
+	// enqueueing one or multiple items
+	q = goqueuepriorityfinite.New(3, goqueuepriorityfinite.TenantCapacity(1),
+		goqueuepriorityfinite.AdmissionFunc(func(priority, length, capacity int) bool {
+			return length == 0 || priority > 0
+		}))
+	defer q.Close()
+
+	assert.False(t, q.TenantEnqueue("tenant", "a", 1))
+	assert.True(t, q.TenantEnqueue("tenant", "b", 0))
+	remaining, overflow = q.TenantEnqueueMultiple("tenant", []interface{}{"c"}, 0)
+	assert.True(t, overflow)
+	assert.Equal(t, []interface{}{"c"}, remaining)
+	assert.Equal(t, uint64(2), q.Stats().Overflowed)
+	assert.Equal(t, uint64(0), q.Stats().Rejected)
+}
+
+// TestCoDel is meant to confirm (using a fake clock) that items with a low
+// priority are dropped once the time in queue has exceeded the target for
+// an interval and that items with a greater priority aren't dropped
+func TestCoDel(t *testing.T) {
+	var dropped []interface{}
+
+	start := time.Unix(0, 0)
+	now := start
+	at := func(d time.Duration) { now = start.Add(d) }
+	q := goqueuepriorityfinite.New(10,
+		func() time.Time { return now },
+		goqueuepriorityfinite.CoDel{
+			Target:   10 * time.Millisecond,
+			Interval: 100 * time.Millisecond,
+			OnDrop: func(item interface{}, priority int) {
+				assert.Equal(t, 0, priority)
+				dropped = append(dropped, item)
+			},
+		})
+	defer q.Close()
+
+	remaining, overflow := q.PriorityEnqueueMultiple([]interface{}{"h1", "h2", "h3"}, 5)
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	remaining, overflow = q.PriorityEnqueueMultiple([]interface{}{"l1", "l2", "l3", "l4"}, 0)
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	for _, c := range []struct {
+		at       time.Duration
+		expected interface{}
+		dropped  []interface{}
+	}{
+		{at: 20 * time.Millisecond, expected: "h1"},
+		{at: 50 * time.Millisecond, expected: "h2"},
+		{at: 120 * time.Millisecond, expected: "h3", dropped: []interface{}{"l1"}},
+		{at: 150 * time.Millisecond, expected: "l2", dropped: []interface{}{"l1"}},
+		{at: 220 * time.Millisecond, expected: "l4", dropped: []interface{}{"l1", "l3"}},
+	} {
+		at(c.at)
+		item, underflow := q.Dequeue()
+		assert.False(t, underflow)
+		assert.Equal(t, c.expected, item)
+		assert.Equal(t, c.dropped, dropped)
+	}
+	at(300 * time.Millisecond)
+	_, underflow := q.Dequeue()
+	assert.True(t, underflow)
+	assert.Equal(t, uint64(2), q.Stats().Evicted)
+
+	//items with a priority greater than the drop priority aren't dropped
+	remaining, overflow = q.PriorityEnqueueMultiple([]interface{}{"h4", "h5"}, 5)
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	at(320 * time.Millisecond)
+	assert.Equal(t, []interface{}{"h4"}, q.DequeueMultiple(1))
+	at(500 * time.Millisecond)
+	assert.Equal(t, []interface{}{"h5"}, q.DequeueMultiple(1))
+	assert.Len(t, dropped, 2)
+
+	//the time in queue is measured using the oldest item that can be
+	// dropped, not the item at the head of the queue
+	at(600 * time.Millisecond)
+	remaining, overflow = q.PriorityEnqueueMultiple([]interface{}{"l5", "l6"}, 0)
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	for _, c := range []struct {
+		at       time.Duration
+		expected interface{}
+	}{
+		{at: 700 * time.Millisecond, expected: "h6"},
+		{at: 800 * time.Millisecond, expected: "h7"},
+	} {
+		at(c.at)
+		assert.False(t, q.PriorityEnqueue(c.expected, 5))
+		item, underflow := q.Dequeue()
+		assert.False(t, underflow)
+		assert.Equal(t, c.expected, item)
+	}
+	assert.Equal(t, []interface{}{"l1", "l3", "l5"}, dropped)
+	assert.Equal(t, []interface{}{"l6"}, q.Flush())
+}
+
+// TestCoDelRecovery is meant to confirm that items stop being dropped once
+// the time in queue of the oldest item that can be dropped falls below the
+// target, even if it's measured while dropping
+func TestCoDelRecovery(t *testing.T) {
+	var dropped []interface{}
+
+	start := time.Unix(0, 0)
+	now := start
+	at := func(d time.Duration) { now = start.Add(d) }
+	q := goqueuepriorityfinite.New(10,
+		func() time.Time { return now },
+		goqueuepriorityfinite.CoDel{
+			Target:   10 * time.Millisecond,
+			Interval: 100 * time.Millisecond,
+			OnDrop: func(item interface{}, priority int) {
+				dropped = append(dropped, item)
+			},
+		})
+	defer q.Close()
+
+	remaining, overflow := q.PriorityEnqueueMultiple([]interface{}{"h1", "h2", "h3", "h4"}, 5)
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	remaining, overflow = q.PriorityEnqueueMultiple([]interface{}{"o1", "o2", "o3"}, 0)
+	assert.False(t, overflow)
+	assert.Empty(t, remaining)
+	for _, c := range []struct {
+		at       time.Duration
+		enqueue  []interface{}
+		expected interface{}
+		dropped  []interface{}
+	}{
+		{at: 200 * time.Millisecond, expected: "h1"},
+		{at: 300 * time.Millisecond, expected: "h2", dropped: []interface{}{"o1"}},
+		{at: 400 * time.Millisecond, expected: "h3", dropped: []interface{}{"o1", "o2"}},
+		{at: 495 * time.Millisecond, enqueue: []interface{}{"n1", "n2"}},
+		{at: 500 * time.Millisecond, expected: "h4", dropped: []interface{}{"o1", "o2", "o3"}},
+		{at: 600 * time.Millisecond, expected: "n1", dropped: []interface{}{"o1", "o2", "o3"}},
+	} {
+		at(c.at)
+		if c.enqueue != nil {
+			remaining, overflow := q.PriorityEnqueueMultiple(c.enqueue, 0)
+			assert.False(t, overflow)
+			assert.Empty(t, remaining)
+			continue
+		}
+		item, underflow := q.Dequeue()
+		assert.False(t, underflow)
+		assert.Equal(t, c.expected, item)
+		assert.Equal(t, c.dropped, dropped)
+	}
+	assert.Equal(t, []interface{}{"n2"}, q.Flush())
+}
+
+// TestKeyEnqueueOrder is meant to confirm that items with the same key are
+// dequeued in the order they were enqueued even when there are many items
+// and they were enqueued at the same time
+func TestKeyEnqueueOrder(t *testing.T) {
+	var expected []interface{}
+
+	now := time.Unix(0, 0)
+	q := goqueuepriorityfinite.New(300, goqueuepriorityfinite.Clock(func() time.Time { return now }))
+	defer q.Close()
+
+	for i := 0; i < 300; i++ {
+		assert.False(t, q.KeyEnqueue(i, i%3, 1))
+	}
+	for priority := 2; priority >= 0; priority-- {
+		for i := priority; i < 300; i += 3 {
+			expected = append(expected, i)
+		}
+	}
+	assert.Equal(t, expected, q.Peek())
+	assert.Equal(t, expected[:100], q.DequeueMultiple(100))
+	assert.Equal(t, expected[100:], q.Flush())
+}
+
+// TestOldestByPriority is meant to confirm that the oldest item for each
+// priority is found when there are many items for each priority
+func TestOldestByPriority(t *testing.T) {
+	start := time.Unix(0, 0)
+	now := start
+	q := goqueuepriorityfinite.New(200, goqueuepriorityfinite.Clock(func() time.Time { return now }))
+	defer q.Close()
+
+	for i := 0; i < 200; i++ {
+		now = start.Add(time.Duration(i+1) * time.Second)
+		assert.False(t, q.PriorityEnqueue(i, i%3))
+	}
+	assert.Equal(t, map[int]int{0: 67, 1: 67, 2: 66}, q.LengthByPriority())
+	oldest := q.OldestByPriority()
+	for priority := 0; priority < 3; priority++ {
+		assert.True(t, start.Add(time.Duration(priority+1)*time.Second).Equal(oldest[priority]), priority)
+	}
+	assert.Equal(t, []interface{}{2, 5, 8, 11}, q.PeekFromHead(4))
+
+	//once the oldest items are dequeued, the next oldest should be found
+	assert.Equal(t, 66, len(q.DequeueMultiple(66)))
+	assert.True(t, start.Add(2*time.Second).Equal(q.OldestByPriority()[1]))
+	assert.Equal(t, []interface{}{1, 4}, q.PeekFromHead(2))
+}
+
+// TestGarbageCollect is meant to confirm that garbage collecting the queue
+// retains its items and the number of items for each priority and tenant
+func TestGarbageCollect(t *testing.T) {
+	q := goqueuepriorityfinite.New(4, goqueuepriorityfinite.TenantCapacity(1))
+	defer q.Close()
+
+	assert.False(t, q.PriorityEnqueue("a", 1))
+	assert.False(t, q.TenantEnqueue("tenant", "b", 2))
+	assert.False(t, q.PriorityEnqueue("c", 2))
+	q.GarbageCollect()
+	assert.Equal(t, 3, q.Length())
+	assert.Equal(t, map[int]int{1: 1, 2: 2}, q.LengthByPriority())
+	assert.Len(t, q.OldestByPriority(), 2)
+	assert.True(t, q.TenantEnqueue("tenant", "d", 2))
+	assert.ElementsMatch(t, []interface{}{"a", "b", "c"}, q.Flush())
+	q.GarbageCollect()
+	assert.Empty(t, q.LengthByPriority())
+	assert.Empty(t, q.OldestByPriority())
+	assert.False(t, q.TenantEnqueue("tenant", "d", 2))
 }
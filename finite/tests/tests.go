@@ -1,8 +1,10 @@
 package priorityfinite_tests
 
 import (
+	"bytes"
 	"context"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +17,15 @@ import (
 
 const casef string = "case: %s"
 
+// prioritized is an example that provides its own priority
+type prioritized struct {
+	goqueue.Example
+}
+
+func (p prioritized) Priority() int {
+	return goqueuepriority.DefaultPriority + 10
+}
+
 func TestPriorityEnqueue(t *testing.T, rate, timeout time.Duration, newQueue func(int) interface {
 	goqueue.Owner
 	goqueue.Dequeuer
@@ -81,6 +92,16 @@ func TestPriorityEnqueue(t *testing.T, rate, timeout time.Duration, newQueue fun
 			//close queue
 			q.Close()
 		}
+
+		//validate that items enqueued without a priority have the default
+		// priority, even if they provide their own priority
+		q := newQueue(2)
+		defer q.Close()
+		assert.False(t, q.PriorityEnqueue(prioritized{goqueue.Example{Int: 1}}))
+		assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 2}, goqueuepriority.DefaultPriority+1))
+		item, underflow := q.Dequeue()
+		assert.False(t, underflow)
+		assert.Equal(t, goqueue.Example{Int: 2}, item)
 		// for cDesc, c := range cases {
 		// 	//TODO: enqueue multiple items
 		// 	//TODO: dequeue multiple
@@ -118,15 +139,46 @@ func TestPriorityEnqueueLossy(t *testing.T, rate, timeout time.Duration, newQueu
 	goqueuepriorityfinite.PriorityEnqueueLossy
 }) func(*testing.T) {
 	return func(t *testing.T) {
-		//generate size and examples
-		size := int(100 * rand.Float64())
-		// examples := goqueue.ExampleGenFloat64(size)
-
 		//create queue
-		q := newQueue(size)
+		q := newQueue(3)
 		defer q.Close()
 
-		//
+		//enqueue items while the queue isn't full, nothing should
+		// be discarded
+		for i, priority := range []int{1, 3, 1} {
+			item, overflow := q.PriorityEnqueueLossy(goqueue.Example{Int: i}, priority)
+			assert.False(t, overflow)
+			assert.Nil(t, item)
+		}
+
+		//enqueue an item with a greater priority while the queue is full,
+		// the item at the tail (the most recent item with the lowest
+		// priority) should be discarded
+		item, overflow := q.PriorityEnqueueLossy(goqueue.Example{Int: 3}, 2)
+		assert.False(t, overflow)
+		assert.Equal(t, goqueue.Example{Int: 2}, item)
+
+		//enqueue an item with a lower priority than the tail while the
+		// queue is full, the item shouldn't be enqueued
+		item, overflow = q.PriorityEnqueueLossy(goqueue.Example{Int: 4}, 0)
+		assert.True(t, overflow)
+		assert.Nil(t, item)
+
+		//enqueue an item with the same priority as the tail, the tail
+		// should be discarded
+		item, overflow = q.PriorityEnqueueLossy(goqueue.Example{Int: 5}, 1)
+		assert.False(t, overflow)
+		assert.Equal(t, goqueue.Example{Int: 0}, item)
+
+		//flush items and validate
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		items := goqueue.MustFlush(q, ctx.Done(), rate)
+		assert.Equal(t, []interface{}{
+			goqueue.Example{Int: 1},
+			goqueue.Example{Int: 3},
+			goqueue.Example{Int: 5},
+		}, items)
 	}
 }
 
@@ -138,14 +190,77 @@ func TestPriorityEnqueueLossyEvent(t *testing.T, rate, timeout time.Duration, ne
 	goqueuepriorityfinite.PriorityEnqueueLossy
 }) func(*testing.T) {
 	return func(t *testing.T) {
-		//generate size and examples
-		size := int(100 * rand.Float64())
-		// examples := goqueue.ExampleGenFloat64(size)
-
 		//create queue
-		q := newQueue(size)
+		q := newQueue(1)
 		defer q.Close()
 
-		//
+		//enqueue an item and validate that signal in is sent
+		signalIn := q.GetSignalIn()
+		item, overflow := q.PriorityEnqueueLossy(goqueue.Example{Int: 1}, 1)
+		assert.False(t, overflow)
+		assert.Nil(t, item)
+		select {
+		case <-signalIn:
+		case <-time.After(timeout):
+			assert.Fail(t, "timed out waiting for signal in")
+		}
+
+		//dequeue the item and validate that signal out is sent
+		signalOut := q.GetSignalOut()
+		item, underflow := q.Dequeue()
+		assert.False(t, underflow)
+		assert.Equal(t, goqueue.Example{Int: 1}, item)
+		select {
+		case <-signalOut:
+		case <-time.After(timeout):
+			assert.Fail(t, "timed out waiting for signal out")
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T, newQueue func(int) interface {
+	goqueue.Owner
+	goqueue.Dequeuer
+	goqueuepriority.PriorityEnqueuer
+	goqueuepriority.WrapperPeeker
+	goqueuepriority.Snapshotter
+}) func(*testing.T) {
+	return func(t *testing.T) {
+		goqueuepriority.RegisterType("goqueue.Example", goqueue.Example{})
+		goqueuepriority.RegisterType("*goqueue.Example", &goqueue.Example{})
+
+		//create queue and enqueue items
+		q := newQueue(4)
+		defer q.Close()
+		assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 1}, 1))
+		assert.False(t, q.PriorityEnqueue(&goqueue.Example{Int: 2}, 2))
+		assert.False(t, q.PriorityEnqueue("three", 1))
+		assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 4}, 2))
+
+		//snapshot the queue
+		buffer := &bytes.Buffer{}
+		err := q.Snapshot(buffer)
+		assert.Nil(t, err)
+		snapshot := buffer.Bytes()
+
+		//restore the snapshot into a new queue and validate
+		// that the wrappers and items are the same
+		restored := newQueue(4)
+		defer restored.Close()
+		assert.False(t, restored.PriorityEnqueue("discarded"))
+		err = restored.Restore(bytes.NewReader(snapshot))
+		assert.Nil(t, err)
+		assert.Equal(t, q.PeekWrappers(), restored.PeekWrappers())
+		assert.Equal(t, q.Flush(), restored.Flush())
+
+		//restore the snapshot into a queue that's too small
+		small := newQueue(2)
+		defer small.Close()
+		err = small.Restore(bytes.NewReader(snapshot))
+		assert.Equal(t, goqueuepriority.ErrSnapshotOverflow, err)
+
+		//restore a snapshot with an unsupported version
+		err = restored.Restore(strings.NewReader(`{"version":0,"wrappers":[]}`))
+		assert.NotNil(t, err)
 	}
 }
@@ -1,5 +1,153 @@
 package priorityfinite
 
+import (
+	"errors"
+	"time"
+
+	priorityqueue "github.com/antonio-alexander/go-queue-priority"
+)
+
+// ErrOverflow is returned when an item can't be enqueued because the
+// queue (or the item's tenant) is full
+var ErrOverflow = errors.New("queue is full")
+
+// ErrRejected is returned when an item can't be enqueued because it was
+// rejected by the admission policy
+var ErrRejected = errors.New("item rejected by admission policy")
+
+// PriorityEnqueueLossy describes an interface for enqueueing an item with an
+// optional priority when the queue is full by discarding the item at the tail
+// of the queue (the most recent item with the lowest priority); the discarded
+// item is returned with overflow false, if the item has a lower priority than
+// the tail, it isn't enqueued and nil is returned with overflow true
 type PriorityEnqueueLossy interface {
 	PriorityEnqueueLossy(item interface{}, priority ...int) (interface{}, bool)
 }
+
+// Weights can be provided to New to dequeue items using (smooth) weighted
+// round robin between priorities rather than strict priority; each priority
+// receives a share of dequeues proportional to its weight. Priorities without
+// a weight have a weight of one and priorities that are empty are skipped, so
+// if only one priority has items, it's dequeued as if it were strict priority
+type Weights map[int]int
+
+// TenantCapacity can be provided to New to limit the number of items any one
+// tenant can have in the queue, once a tenant has reached its capacity, any
+// items enqueued for that tenant will overflow; items enqueued without a tenant
+// aren't limited
+type TenantCapacity int
+
+// DynamicPriority can be provided to New to re-evaluate the priority of items
+// lazily; the priority of every item is re-evaluated using Score before items
+// are dequeued and when Rescore is called, but no more often than Interval.
+// Items keep the priority they were enqueued with until they're rescored and
+// items with the same priority are dequeued in the order they were enqueued
+type DynamicPriority struct {
+	Score    priorityqueue.PriorityFunc
+	Interval time.Duration
+}
+
+// Stats describes the current state of the queue and the number of
+// items that have moved through it since it was created; Highest and
+// Lowest are only meaningful if Depth is greater than zero
+type Stats struct {
+	Depth      int    `json:"depth"`
+	Enqueued   uint64 `json:"enqueued"`
+	Dequeued   uint64 `json:"dequeued"`
+	Overflowed uint64 `json:"overflowed"`
+	Rejected   uint64 `json:"rejected"`
+	Evicted    uint64 `json:"evicted"`
+	Highest    int    `json:"highest"`
+	Lowest     int    `json:"lowest"`
+}
+
+// Statistics describes an interface to read statistics of the queue
+// without having to peek every item
+type Statistics interface {
+	//LengthByPriority will return the number of items for each priority
+	LengthByPriority() map[int]int
+
+	//OldestByPriority will return when the oldest item for each
+	// priority was enqueued
+	OldestByPriority() map[int]time.Time
+
+	//Stats will return the current statistics of the queue
+	Stats() Stats
+}
+
+// Watermarks can be provided to New to be notified when the number of items
+// in the queue crosses a watermark (e.g. to throttle producers before the
+// queue overflows); the high watermark is crossed once the length reaches High
+// and the low watermark is crossed once the length drops to Low after the high
+// watermark was crossed. OnHigh and OnLow are optional and are called (with
+// the length of the queue) while the queue is locked, so they must not use
+// the queue. A High of zero disables watermarks; when the queue is created or
+// resized, a High greater than the size of the queue is reduced to the size
+// (and restored if the queue grows), a negative Low is increased to zero and
+// if Low isn't less than High, the watermarks are ignored until the queue is
+// resized such that it is
+type Watermarks struct {
+	High   int
+	Low    int
+	OnHigh func(length int)
+	OnLow  func(length int)
+}
+
+// WatermarkEvent describes an interface to be signaled when the number of
+// items in the queue crosses the configured watermarks
+type WatermarkEvent interface {
+	//GetSignalHigh will return a channel that's signaled when the
+	// high watermark is crossed
+	GetSignalHigh() (signal <-chan struct{})
+
+	//GetSignalLow will return a channel that's signaled when the
+	// low watermark is crossed
+	GetSignalLow() (signal <-chan struct{})
+}
+
+// AdmissionStep describes the minimum priority of items admitted once the
+// queue is at least Fill (between zero and one) full
+type AdmissionStep struct {
+	Fill        float64
+	MinPriority int
+}
+
+// AdmissionSteps can be provided to New to reject items with a low priority as
+// the queue fills up (e.g. reject items with a priority less than ten once the
+// queue is 80% full); the step with the greatest fill that the queue has
+// reached is used and items are admitted if there's no such step
+type AdmissionSteps []AdmissionStep
+
+// AdmissionFunc can be provided to New to determine if an item with the given
+// priority should be admitted given the length and capacity of the queue
+type AdmissionFunc func(priority, length, capacity int) (admit bool)
+
+// TryEnqueuer describes an interface for enqueueing items that reports
+// why an item couldn't be enqueued
+type TryEnqueuer interface {
+	//TryEnqueue can be used to enqueue a single item with an optional
+	// priority, it will return ErrOverflow if the queue is full or
+	// ErrRejected if the item was rejected by the admission policy
+	TryEnqueue(item interface{}, priority ...int) (err error)
+}
+
+// Clock can be provided to New to determine the current time, it's used
+// when items are enqueued and to measure how long items have been in the
+// queue (e.g. to use a fake clock for testing); a func() time.Time can also
+// be provided
+type Clock func() time.Time
+
+// CoDel can be provided to New to drop items using controlled delay (CoDel)
+// active queue management; when the time items spend in the queue (measured
+// using the oldest item with a priority of at most DropPriority as items are
+// dequeued) has exceeded Target for at least Interval, that oldest item is
+// dropped as items are dequeued, at an increasing rate, until the time in
+// queue (measured again after each drop) falls below Target. Dropped items
+// are evicted and provided to OnDrop (if set) which is called while the
+// queue is locked
+type CoDel struct {
+	Target       time.Duration
+	Interval     time.Duration
+	DropPriority int
+	OnDrop       func(item interface{}, priority int)
+}
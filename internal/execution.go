@@ -34,7 +34,7 @@ func Enqueue(items []*goqueuepriority.Wrapper, item *goqueuepriority.Wrapper) ([
 
 // Dequeue can be used to remove an item from the queue and reduce its
 // capacity by one
-func Dequeue(items []*goqueuepriority.Wrapper) (interface{}, []*goqueuepriority.Wrapper, bool) {
+func Dequeue(items []*goqueuepriority.Wrapper) (*goqueuepriority.Wrapper, []*goqueuepriority.Wrapper, bool) {
 	if len(items) <= 0 {
 		return nil, items, true
 	}
@@ -44,13 +44,22 @@ func Dequeue(items []*goqueuepriority.Wrapper) (interface{}, []*goqueuepriority.
 	if len(items) > 0 {
 		items = items[:len(items)-1] //truncate the slice
 	}
-	return item.Item, items, false
+	return item, items, false
+}
+
+// Remove can be used to remove the item at the given index while maintaining
+// the order (and capacity) of the remaining items
+func Remove(items []*goqueuepriority.Wrapper, index int) (*goqueuepriority.Wrapper, []*goqueuepriority.Wrapper) {
+	item := items[index]
+	copy(items[index:], items[index+1:])
+	items[len(items)-1] = nil
+	return item, items[:len(items)-1]
 }
 
 // DequeueMultiple will return a number of items less than or equal to the value of
 // n while maintaining the input data on the second slice of interface, it will return
 // true if there are no items to dequeue
-func DequeueMultiple(n int, wrappers []*goqueuepriority.Wrapper) ([]interface{}, []*goqueuepriority.Wrapper, bool) {
+func DequeueMultiple(n int, wrappers []*goqueuepriority.Wrapper) ([]*goqueuepriority.Wrapper, []*goqueuepriority.Wrapper, bool) {
 	var length int
 
 	//get the length of the data, underflow if no data, then
@@ -61,11 +70,11 @@ func DequeueMultiple(n int, wrappers []*goqueuepriority.Wrapper) ([]interface{},
 	if n > length {
 		n = length
 	}
-	items := make([]interface{}, 0, n)
+	items := make([]*goqueuepriority.Wrapper, 0, n)
 	//TODO: do this at once instead of using dequeue
 	// over and over again
 	for i := 0; i < n; i++ {
-		var item interface{}
+		var item *goqueuepriority.Wrapper
 
 		item, wrappers, _ = Dequeue(wrappers)
 		items = append(items, item)
@@ -73,6 +82,19 @@ func DequeueMultiple(n int, wrappers []*goqueuepriority.Wrapper) ([]interface{},
 	return items, wrappers, false
 }
 
+// Items can be used to unwrap a slice of wrappers, it will return
+// nil if there are no wrappers
+func Items(wrappers []*goqueuepriority.Wrapper) []interface{} {
+	if len(wrappers) == 0 {
+		return nil
+	}
+	items := make([]interface{}, 0, len(wrappers))
+	for _, wrapper := range wrappers {
+		items = append(items, wrapper.Item)
+	}
+	return items
+}
+
 // SendSignal will perform a non-blocking send with or without
 // a timeout depending on whether ConfigSignalTimeout is greater
 // than 0
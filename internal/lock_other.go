@@ -0,0 +1,17 @@
+//go:build !unix
+
+package internal
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned by Lock if the file is already locked
+var ErrLocked = errors.New("locked by another process")
+
+// Lock is only supported on unix, elsewhere it's up to the caller to
+// ensure that a file is only opened once
+func Lock(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package internal
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// ErrLocked is returned by Lock if the file is already locked
+var ErrLocked = errors.New("locked by another process")
+
+// Lock will take an exclusive lock on the file without blocking, the
+// lock is released when the file is closed
+func Lock(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrLocked
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+package priority
+
+import (
+	"sort"
+	"sync"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+)
+
+// MergeSource describes the functionality a queue must provide
+// to be merged
+type MergeSource interface {
+	goqueue.Dequeuer
+	goqueue.Event
+	WrapperPeeker
+}
+
+type merged struct {
+	sync.RWMutex
+	sources   []MergeSource
+	signalIn  chan struct{}
+	signalOut chan struct{}
+}
+
+// Merge can be used to create a view of one or more queues that dequeues the
+// item with the highest priority across all of the queues (items with the same
+// priority are dequeued in the order they were enqueued). Items remain within
+// their source queue until they're dequeued. Keep in mind that the signals of
+// each source are forwarded to the signals of the view so they're no longer
+// available to other consumers of the source queues and the signals of the
+// view will be nil once all of the source queues have been closed
+func Merge(queues ...MergeSource) interface {
+	goqueue.Dequeuer
+	goqueue.Peeker
+	goqueue.Event
+} {
+	m := &merged{
+		sources:   queues,
+		signalIn:  make(chan struct{}, len(queues)+1),
+		signalOut: make(chan struct{}, len(queues)+1),
+	}
+	m.forward(func(q MergeSource) func() <-chan struct{} { return q.GetSignalIn },
+		func() { m.signalIn = nil }, m.signalIn)
+	m.forward(func(q MergeSource) func() <-chan struct{} { return q.GetSignalOut },
+		func() { m.signalOut = nil }, m.signalOut)
+	return m
+}
+
+// forward will start a goroutine for each source that forwards its signal
+// to the given signal until the source is closed, once all of the sources
+// are closed, the signal is closed and cleared
+func (m *merged) forward(getSignal func(MergeSource) func() <-chan struct{}, clear func(), signal chan struct{}) {
+	wg := new(sync.WaitGroup)
+	for _, source := range m.sources {
+		wg.Add(1)
+		go func(getSignal func() <-chan struct{}) {
+			defer wg.Done()
+
+			for waitSignal(getSignal, nil) {
+				select {
+				default:
+				case signal <- struct{}{}:
+				}
+			}
+		}(getSignal(source))
+	}
+	go func() {
+		wg.Wait()
+		m.Lock()
+		defer m.Unlock()
+		close(signal)
+		clear()
+	}()
+}
+
+// head will return the index of the source whose head should be
+// dequeued next, it will return -1 if all of the sources are empty
+func (m *merged) head() int {
+	var best *Wrapper
+
+	index := -1
+	for i, source := range m.sources {
+		wrapper, underflow := source.PeekHeadWrapper()
+		if underflow {
+			continue
+		}
+		if best == nil || Less(wrapper, best) {
+			best, index = wrapper, i
+		}
+	}
+	return index
+}
+
+// wrappers will return the wrappers of all sources in the
+// order they'd be dequeued
+func (m *merged) wrappers() []*Wrapper {
+	var wrappers []*Wrapper
+
+	for _, source := range m.sources {
+		wrappers = append(wrappers, source.PeekWrappers()...)
+	}
+	sort.SliceStable(wrappers, func(i, j int) bool {
+		return Less(wrappers[i], wrappers[j])
+	})
+	return wrappers
+}
+
+func (m *merged) dequeue() (interface{}, bool) {
+	//KIM: if the source is dequeued by another consumer between the peek
+	// and the dequeue, the next item of that source will be dequeued
+	for {
+		index := m.head()
+		if index < 0 {
+			return nil, true
+		}
+		if item, underflow := m.sources[index].Dequeue(); !underflow {
+			return item, false
+		}
+	}
+}
+
+func (m *merged) GetSignalIn() <-chan struct{} {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.signalIn
+}
+
+func (m *merged) GetSignalOut() <-chan struct{} {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.signalOut
+}
+
+func (m *merged) Dequeue() (interface{}, bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.dequeue()
+}
+
+func (m *merged) DequeueMultiple(n int) []interface{} {
+	m.Lock()
+	defer m.Unlock()
+
+	var items []interface{}
+
+	for i := 0; i < n; i++ {
+		item, underflow := m.dequeue()
+		if underflow {
+			break
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func (m *merged) Flush() []interface{} {
+	m.Lock()
+	defer m.Unlock()
+
+	var items []interface{}
+
+	for {
+		item, underflow := m.dequeue()
+		if underflow {
+			return items
+		}
+		items = append(items, item)
+	}
+}
+
+func (m *merged) Peek() []interface{} {
+	m.RLock()
+	defer m.RUnlock()
+
+	wrappers := m.wrappers()
+	items := make([]interface{}, 0, len(wrappers))
+	for _, wrapper := range wrappers {
+		items = append(items, wrapper.Item)
+	}
+	return items
+}
+
+func (m *merged) PeekHead() (interface{}, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	index := m.head()
+	if index < 0 {
+		return nil, true
+	}
+	wrapper, underflow := m.sources[index].PeekHeadWrapper()
+	if underflow {
+		return nil, true
+	}
+	return wrapper.Item, false
+}
+
+func (m *merged) PeekFromHead(n int) []interface{} {
+	m.RLock()
+	defer m.RUnlock()
+
+	wrappers := m.wrappers()
+	if len(wrappers) == 0 {
+		return nil
+	}
+	if n > len(wrappers) {
+		n = len(wrappers)
+	}
+	items := make([]interface{}, 0, n)
+	for _, wrapper := range wrappers[:n] {
+		items = append(items, wrapper.Item)
+	}
+	return items
+}
@@ -0,0 +1,10 @@
+// Copyright 2023 antonio-alexander. All rights reserved.
+// Use of this source code is governed by an MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package metrics provides a collector that can be attached to a priority queue to
+keep track of counters, gauges and time-in-queue histograms for each priority as
+well as publishers for expvar and the prometheus text format
+*/
+package metrics
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+)
+
+type collector struct {
+	sync.RWMutex
+	buckets    []time.Duration
+	clock      Clock
+	priorities map[int]*Metrics
+}
+
+// New can be used to create a collector, the optional parameters can be used
+// to further configure the collector:
+//   - time.Duration or []time.Duration: the buckets of the time-in-queue
+//     histogram, if no buckets are provided the DefaultBuckets will be used
+//   - Clock: determines the current time used to measure the time-in-queue,
+//     it should be the clock of the queue (a func() time.Time can also be
+//     provided)
+func New(parameters ...interface{}) Collector {
+	var buckets []time.Duration
+
+	c := &collector{
+		clock:      time.Now,
+		priorities: make(map[int]*Metrics),
+	}
+	for _, parameter := range parameters {
+		switch p := parameter.(type) {
+		case time.Duration:
+			buckets = append(buckets, p)
+		case []time.Duration:
+			buckets = append(buckets, p...)
+		case Clock:
+			c.clock = p
+		case func() time.Time:
+			c.clock = p
+		}
+	}
+	if len(buckets) == 0 {
+		buckets = append(buckets, DefaultBuckets...)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	c.buckets = buckets
+	return c
+}
+
+func (c *collector) metrics(priority int) *Metrics {
+	m, ok := c.priorities[priority]
+	if !ok {
+		m = &Metrics{
+			Priority: priority,
+			TimeInQueue: Histogram{
+				Buckets: c.buckets,
+				Counts:  make([]uint64, len(c.buckets)+1),
+			},
+		}
+		c.priorities[priority] = m
+	}
+	return m
+}
+
+func (c *collector) Enqueued(wrapper *goqueuepriority.Wrapper) {
+	c.Lock()
+	defer c.Unlock()
+
+	m := c.metrics(wrapper.Priority)
+	m.Enqueued++
+	m.Depth++
+}
+
+func (c *collector) Dequeued(wrapper *goqueuepriority.Wrapper) {
+	c.Lock()
+	defer c.Unlock()
+
+	m := c.metrics(wrapper.Priority)
+	m.Dequeued++
+	m.Depth--
+	timeInQueue := time.Duration(c.clock().UnixNano() - wrapper.EnqueuedAt)
+	if timeInQueue < 0 {
+		timeInQueue = 0
+	}
+	i := sort.Search(len(c.buckets), func(i int) bool { return timeInQueue <= c.buckets[i] })
+	m.TimeInQueue.Counts[i]++
+	m.TimeInQueue.Count++
+	m.TimeInQueue.Sum += timeInQueue
+}
+
+func (c *collector) Overflowed(priority int) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.metrics(priority).Overflowed++
+}
+
+func (c *collector) Rejected(priority int) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.metrics(priority).Rejected++
+}
+
+func (c *collector) Evicted(wrapper *goqueuepriority.Wrapper) {
+	c.Lock()
+	defer c.Unlock()
+
+	m := c.metrics(wrapper.Priority)
+	m.Evicted++
+	m.Depth--
+}
+
+func (c *collector) Reprioritized(wrapper *goqueuepriority.Wrapper, from int) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.metrics(from).Depth--
+	c.metrics(wrapper.Priority).Depth++
+}
+
+func (c *collector) Snapshot() []Metrics {
+	c.RLock()
+	defer c.RUnlock()
+
+	snapshot := make([]Metrics, 0, len(c.priorities))
+	for _, m := range c.priorities {
+		metrics := *m
+		metrics.TimeInQueue.Counts = append([]uint64{}, m.TimeInQueue.Counts...)
+		snapshot = append(snapshot, metrics)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Priority > snapshot[j].Priority
+	})
+	return snapshot
+}
@@ -0,0 +1,121 @@
+package metrics_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	metrics "github.com/antonio-alexander/go-queue-priority/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	collector := metrics.New(time.Hour)
+	q := goqueuepriorityfinite.New(3, collector)
+	defer q.Close()
+
+	//enqueue three items, the last one should overflow
+	assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 1}, 1))
+	assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 2}, 1))
+	assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 3}, 2))
+	assert.True(t, q.PriorityEnqueue(goqueue.Example{Int: 4}, 2))
+
+	//dequeue the highest priority item and evict one item by resizing
+	item, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, goqueue.Example{Int: 3}, item)
+	assert.Len(t, q.Resize(1), 1)
+
+	snapshot := collector.Snapshot()
+	if assert.Len(t, snapshot, 2) {
+		assert.Equal(t, 2, snapshot[0].Priority)
+		assert.Equal(t, uint64(1), snapshot[0].Enqueued)
+		assert.Equal(t, uint64(1), snapshot[0].Dequeued)
+		assert.Equal(t, uint64(1), snapshot[0].Overflowed)
+		assert.Equal(t, int64(0), snapshot[0].Depth)
+		assert.Equal(t, uint64(1), snapshot[0].TimeInQueue.Count)
+		assert.Equal(t, []uint64{1, 0}, snapshot[0].TimeInQueue.Counts)
+		assert.Equal(t, 1, snapshot[1].Priority)
+		assert.Equal(t, uint64(2), snapshot[1].Enqueued)
+		assert.Equal(t, uint64(1), snapshot[1].Evicted)
+		assert.Equal(t, int64(1), snapshot[1].Depth)
+	}
+}
+
+func TestCollectorRejected(t *testing.T) {
+	collector := metrics.New()
+	q := goqueuepriorityfinite.New(2, collector,
+		goqueuepriorityfinite.AdmissionSteps{{Fill: 0.5, MinPriority: 2}})
+	defer q.Close()
+
+	//once the queue is half full, items with a priority less than two
+	// should be rejected and counted separately from overflows
+	assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 1}, 1))
+	assert.True(t, q.PriorityEnqueue(goqueue.Example{Int: 2}, 1))
+	assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 3}, 2))
+	assert.True(t, q.PriorityEnqueue(goqueue.Example{Int: 4}, 2))
+	snapshot := collector.Snapshot()
+	if assert.Len(t, snapshot, 2) {
+		assert.Equal(t, uint64(0), snapshot[0].Rejected)
+		assert.Equal(t, uint64(1), snapshot[0].Overflowed)
+		assert.Equal(t, uint64(1), snapshot[1].Rejected)
+		assert.Equal(t, uint64(0), snapshot[1].Overflowed)
+	}
+}
+
+// TestCollectorClock is meant to confirm that the time-in-queue is measured
+// using the queue's clock and that items remaining in the queue when it's
+// closed aren't counted as dequeued
+func TestCollectorClock(t *testing.T) {
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+	collector := metrics.New(time.Second, clock)
+	q := goqueuepriorityfinite.New(2, collector, clock)
+
+	assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 1}, 1))
+	assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 2}, 1))
+	now = now.Add(2 * time.Second)
+	item, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, goqueue.Example{Int: 1}, item)
+	assert.Len(t, q.Close(), 1)
+	snapshot := collector.Snapshot()
+	if assert.Len(t, snapshot, 1) {
+		assert.Equal(t, uint64(1), snapshot[0].Dequeued)
+		assert.Equal(t, int64(1), snapshot[0].Depth)
+		assert.Equal(t, 2*time.Second, snapshot[0].TimeInQueue.Sum)
+		assert.Equal(t, []uint64{0, 1}, snapshot[0].TimeInQueue.Counts)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	collector := metrics.New(time.Second)
+	collector.Overflowed(3)
+	collector.Rejected(3)
+
+	//validate prometheus output
+	buffer := &bytes.Buffer{}
+	err := metrics.WritePrometheus(buffer, map[string]metrics.Collector{"commands": collector})
+	assert.Nil(t, err)
+	output := buffer.String()
+	assert.Contains(t, output, "# TYPE goqueue_priority_overflowed_total counter\n")
+	assert.Contains(t, output, `goqueue_priority_overflowed_total{queue="commands",priority="3"} 1`+"\n")
+	assert.Contains(t, output, `goqueue_priority_rejected_total{queue="commands",priority="3"} 1`+"\n")
+	assert.Contains(t, output, `goqueue_priority_time_in_queue_seconds_bucket{queue="commands",priority="3",le="1"} 0`+"\n")
+	assert.Contains(t, output, `goqueue_priority_time_in_queue_seconds_bucket{queue="commands",priority="3",le="+Inf"} 0`+"\n")
+
+	//validate expvar output
+	metrics.PublishExpvar("test_publish", collector)
+	var snapshot []metrics.Metrics
+	err = json.Unmarshal([]byte(expvar.Get("test_publish").String()), &snapshot)
+	assert.Nil(t, err)
+	if assert.Len(t, snapshot, 1) {
+		assert.Equal(t, uint64(1), snapshot[0].Overflowed)
+		assert.Equal(t, uint64(1), snapshot[0].Rejected)
+	}
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prometheusPrefix is prepended to the name of every metric written
+// in the prometheus text format
+const prometheusPrefix string = "goqueue_priority_"
+
+// PublishExpvar can be used to publish the metrics of a collector with
+// expvar under the given name, like expvar.Publish, this will panic if
+// the name has already been published
+func PublishExpvar(name string, collector Collector) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return collector.Snapshot()
+	}))
+}
+
+// WritePrometheus can be used to write the metrics of one or more collectors
+// (keyed by queue name) using the prometheus text exposition format
+func WritePrometheus(w io.Writer, collectors map[string]Collector) error {
+	type sample struct {
+		queue   string
+		metrics Metrics
+	}
+
+	var samples []sample
+
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, metrics := range collectors[name].Snapshot() {
+			samples = append(samples, sample{queue: name, metrics: metrics})
+		}
+	}
+	writer := bufio.NewWriter(w)
+	counters := []struct {
+		name, help string
+		value      func(Metrics) uint64
+	}{
+		{"enqueued_total", "Number of items enqueued.", func(m Metrics) uint64 { return m.Enqueued }},
+		{"dequeued_total", "Number of items dequeued.", func(m Metrics) uint64 { return m.Dequeued }},
+		{"overflowed_total", "Number of items that could not be enqueued because the queue was full.", func(m Metrics) uint64 { return m.Overflowed }},
+		{"rejected_total", "Number of items that could not be enqueued because they were rejected by the admission policy.", func(m Metrics) uint64 { return m.Rejected }},
+		{"evicted_total", "Number of items removed from the queue without being dequeued.", func(m Metrics) uint64 { return m.Evicted }},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(writer, "# HELP %s%s %s\n", prometheusPrefix, counter.name, counter.help)
+		fmt.Fprintf(writer, "# TYPE %s%s counter\n", prometheusPrefix, counter.name)
+		for _, s := range samples {
+			fmt.Fprintf(writer, "%s%s{%s} %d\n", prometheusPrefix, counter.name,
+				labels(s.queue, s.metrics.Priority), counter.value(s.metrics))
+		}
+	}
+	fmt.Fprintf(writer, "# HELP %sdepth Number of items currently in the queue.\n", prometheusPrefix)
+	fmt.Fprintf(writer, "# TYPE %sdepth gauge\n", prometheusPrefix)
+	for _, s := range samples {
+		fmt.Fprintf(writer, "%sdepth{%s} %d\n", prometheusPrefix,
+			labels(s.queue, s.metrics.Priority), s.metrics.Depth)
+	}
+	fmt.Fprintf(writer, "# HELP %stime_in_queue_seconds Time items spent in the queue before being dequeued.\n", prometheusPrefix)
+	fmt.Fprintf(writer, "# TYPE %stime_in_queue_seconds histogram\n", prometheusPrefix)
+	for _, s := range samples {
+		var cumulative uint64
+
+		histogram := s.metrics.TimeInQueue
+		l := labels(s.queue, s.metrics.Priority)
+		for i, bucket := range histogram.Buckets {
+			cumulative += histogram.Counts[i]
+			fmt.Fprintf(writer, "%stime_in_queue_seconds_bucket{%s,le=\"%s\"} %d\n", prometheusPrefix,
+				l, strconv.FormatFloat(bucket.Seconds(), 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(writer, "%stime_in_queue_seconds_bucket{%s,le=\"+Inf\"} %d\n", prometheusPrefix, l, histogram.Count)
+		fmt.Fprintf(writer, "%stime_in_queue_seconds_sum{%s} %s\n", prometheusPrefix, l,
+			strconv.FormatFloat(histogram.Sum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(writer, "%stime_in_queue_seconds_count{%s} %d\n", prometheusPrefix, l, histogram.Count)
+	}
+	return writer.Flush()
+}
+
+// Handler can be used to serve the metrics of one or more collectors
+// (keyed by queue name) in the prometheus text exposition format
+func Handler(collectors map[string]Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := WritePrometheus(w, collectors); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func labels(queue string, priority int) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return fmt.Sprintf(`queue="%s",priority="%d"`, replacer.Replace(queue), priority)
+}
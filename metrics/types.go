@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"time"
+
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+)
+
+// DefaultBuckets are the upper bounds of the time-in-queue histogram
+// used when no buckets are provided
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	time.Minute,
+}
+
+// Clock can be provided to New to determine the current time when
+// measuring how long items have been in the queue, it should be the
+// same clock used by the queue to set when items are enqueued
+type Clock func() time.Time
+
+// Histogram describes the distribution of the time items spent in the
+// queue, Counts has one more element than Buckets, the last element is
+// the number of observations greater than the largest bucket
+type Histogram struct {
+	Buckets []time.Duration `json:"buckets"`
+	Counts  []uint64        `json:"counts"`
+	Count   uint64          `json:"count"`
+	Sum     time.Duration   `json:"sum"`
+}
+
+// Metrics describes the counters and gauges for a single priority
+type Metrics struct {
+	Priority    int       `json:"priority"`
+	Enqueued    uint64    `json:"enqueued"`
+	Dequeued    uint64    `json:"dequeued"`
+	Overflowed  uint64    `json:"overflowed"`
+	Rejected    uint64    `json:"rejected"`
+	Evicted     uint64    `json:"evicted"`
+	Depth       int64     `json:"depth"`
+	TimeInQueue Histogram `json:"time_in_queue"`
+}
+
+// Collector describes a priority queue observer that can provide
+// a point in time copy of its metrics
+type Collector interface {
+	goqueuepriority.Observer
+	goqueuepriority.RejectionObserver
+
+	//Snapshot will return a copy of the metrics for each priority
+	// sorted from highest to lowest priority
+	Snapshot() []Metrics
+}
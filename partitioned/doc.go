@@ -0,0 +1,12 @@
+// Copyright 2023 antonio-alexander. All rights reserved.
+// Use of this source code is governed by an MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package prioritypartitioned provides a finite priority queue where items are
+grouped by a partition key; items with the same key are always dequeued in the
+order they were enqueued and only the item at the head of each partition
+competes on priority. Items enqueued without a partition key aren't partitioned
+and behave as they would in a finite priority queue
+*/
+package prioritypartitioned
@@ -0,0 +1,596 @@
+package prioritypartitioned
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	priorityqueue "github.com/antonio-alexander/go-queue-priority"
+	priorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	internal "github.com/antonio-alexander/go-queue-priority/internal"
+	finite "github.com/antonio-alexander/go-queue/finite"
+)
+
+type queuePartitioned struct {
+	sync.RWMutex
+	size         int
+	length       int
+	signalIn     chan struct{}
+	signalOut    chan struct{}
+	partitions   map[string][]*priorityqueue.Wrapper
+	observer     priorityqueue.Observer
+	priorityFunc priorityqueue.PriorityFunc
+}
+
+// New can be used to create a finite partitioned priority queue with the given size,
+// the optional parameters can be used to further configure the queue:
+//   - priorityqueue.Observer: will be notified as items move through the queue
+//   - priorityqueue.PriorityFunc: determines the priority of items enqueued using
+//     Enqueue, otherwise items that implement priorityqueue.Prioritizer provide their own
+//     priority (items enqueued using PriorityEnqueue without a priority have the
+//     default priority)
+func New(size int, parameters ...interface{}) interface {
+	goqueue.Owner
+	goqueue.GarbageCollecter
+	goqueue.Length
+	goqueue.Event
+	goqueue.Peeker
+	goqueue.Dequeuer
+	goqueue.Enqueuer
+	finite.EnqueueLossy
+	finite.Resizer
+	finite.Capacity
+	priorityqueue.PriorityEnqueuer
+	priorityqueue.WrapperPeeker
+	priorityqueue.WrapperHeadPeeker
+	priorityqueue.Snapshotter
+	priorityfinite.PriorityEnqueueLossy
+	PartitionEnqueuer
+} {
+	if size < 1 {
+		size = 1
+	}
+	q := &queuePartitioned{
+		size:       size,
+		signalIn:   make(chan struct{}, size),
+		signalOut:  make(chan struct{}, size),
+		partitions: make(map[string][]*priorityqueue.Wrapper),
+	}
+	for _, parameter := range parameters {
+		switch p := parameter.(type) {
+		case priorityqueue.Observer:
+			q.observer = p
+		case priorityqueue.PriorityFunc:
+			q.priorityFunc = p
+		case func(item interface{}) int:
+			q.priorityFunc = p
+		}
+	}
+	return q
+}
+
+// partitionKey will return the partition key for the item, if the item
+// doesn't implement Partitioner, it isn't partitioned
+func partitionKey(item interface{}) string {
+	if partitioner, ok := item.(Partitioner); ok {
+		return partitioner.PartitionKey()
+	}
+	return ""
+}
+
+// less will determine if the head of partition a should be dequeued before the
+// head of partition b, if the wrappers are equal, the keys are compared so that
+// the order is deterministic
+func less(keyA string, a *priorityqueue.Wrapper, keyB string, b *priorityqueue.Wrapper) bool {
+	if priorityqueue.Less(a, b) {
+		return true
+	}
+	if priorityqueue.Less(b, a) {
+		return false
+	}
+	return keyA < keyB
+}
+
+// order will return the keys of the partitions in the order their items
+// would be dequeued, a key will be repeated once for each of its items
+func (q *queuePartitioned) order(n int) []string {
+	if n > q.length {
+		n = q.length
+	}
+	offsets := make(map[string]int, len(q.partitions))
+	keys := make([]string, 0, n)
+	for len(keys) < n {
+		var bestKey string
+		var best *priorityqueue.Wrapper
+
+		for key, partition := range q.partitions {
+			offset := offsets[key]
+			if offset >= len(partition) {
+				continue
+			}
+			if best == nil || less(key, partition[offset], bestKey, best) {
+				bestKey, best = key, partition[offset]
+			}
+		}
+		offsets[bestKey]++
+		keys = append(keys, bestKey)
+	}
+	return keys
+}
+
+// head will return the key of the partition whose head should
+// be dequeued next
+func (q *queuePartitioned) head() (string, bool) {
+	keys := q.order(1)
+	if len(keys) == 0 {
+		return "", false
+	}
+	return keys[0], true
+}
+
+// wrappers will return up to n wrappers in the order they'd be dequeued
+func (q *queuePartitioned) wrappers(n int) []*priorityqueue.Wrapper {
+	keys := q.order(n)
+	offsets := make(map[string]int, len(q.partitions))
+	wrappers := make([]*priorityqueue.Wrapper, 0, len(keys))
+	for _, key := range keys {
+		wrappers = append(wrappers, q.partitions[key][offsets[key]])
+		offsets[key]++
+	}
+	return wrappers
+}
+
+func (q *queuePartitioned) dequeue(n int) []*priorityqueue.Wrapper {
+	keys := q.order(n)
+	wrappers := make([]*priorityqueue.Wrapper, 0, len(keys))
+	for _, key := range keys {
+		partition := q.partitions[key]
+		wrappers = append(wrappers, partition[0])
+		partition[0] = nil
+		if partition = partition[1:]; len(partition) == 0 {
+			delete(q.partitions, key)
+		} else {
+			q.partitions[key] = partition
+		}
+		q.length--
+	}
+	return wrappers
+}
+
+func (q *queuePartitioned) enqueue(key string, item interface{}, priority int) bool {
+	if q.length >= q.size {
+		if q.observer != nil {
+			q.observer.Overflowed(priority)
+		}
+		return true
+	}
+	wrapper := &priorityqueue.Wrapper{
+		Item:       item,
+		Priority:   priority,
+		EnqueuedAt: time.Now().UnixNano(),
+		Partition:  key,
+	}
+	q.insert(wrapper)
+	if q.observer != nil {
+		q.observer.Enqueued(wrapper)
+	}
+	return false
+}
+
+// insert will place the wrapper at the tail of its partition, items that
+// aren't partitioned are kept in a single partition sorted by priority
+func (q *queuePartitioned) insert(wrapper *priorityqueue.Wrapper) {
+	partition := q.partitions[wrapper.Partition]
+	if wrapper.Partition != "" {
+		q.partitions[wrapper.Partition] = append(partition, wrapper)
+		q.length++
+		return
+	}
+	index := sort.Search(len(partition), func(i int) bool {
+		return priorityqueue.Less(wrapper, partition[i])
+	})
+	partition = append(partition, nil)
+	copy(partition[index+1:], partition[index:])
+	partition[index] = wrapper
+	q.partitions[""] = partition
+	q.length++
+}
+
+func (q *queuePartitioned) dequeued(wrappers ...*priorityqueue.Wrapper) {
+	if q.observer != nil {
+		for _, wrapper := range wrappers {
+			q.observer.Dequeued(wrapper)
+		}
+	}
+}
+
+func (q *queuePartitioned) evicted(wrappers ...*priorityqueue.Wrapper) {
+	if q.observer != nil {
+		for _, wrapper := range wrappers {
+			q.observer.Evicted(wrapper)
+		}
+	}
+}
+
+func (q *queuePartitioned) Close() []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	remainingWrappers := q.dequeue(q.length)
+	if q.signalIn != nil {
+		select {
+		default:
+			close(q.signalIn)
+		case <-q.signalIn:
+		}
+	}
+	if q.signalOut != nil {
+		select {
+		default:
+			close(q.signalOut)
+		case <-q.signalOut:
+		}
+	}
+	q.partitions, q.signalIn, q.signalOut = nil, nil, nil
+	q.size = 0
+	return internal.Items(remainingWrappers)
+}
+
+func (q *queuePartitioned) GarbageCollect() {
+	q.Lock()
+	defer q.Unlock()
+
+	//create a new map and copy each partition into a new slice
+	// so that the old slices can be garbage collected
+	partitions := make(map[string][]*priorityqueue.Wrapper, len(q.partitions))
+	for key, partition := range q.partitions {
+		partitions[key] = append(make([]*priorityqueue.Wrapper, 0, len(partition)), partition...)
+	}
+	q.partitions = partitions
+}
+
+func (q *queuePartitioned) Resize(newSize int) []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	var discardedItems []*priorityqueue.Wrapper
+
+	if newSize < 1 {
+		newSize = 1
+	}
+	if newSize == q.size {
+		return nil
+	}
+	if q.length > newSize {
+		discardedItems = q.dequeue(q.length - newSize)
+	}
+	if q.signalIn != nil {
+		select {
+		default:
+			close(q.signalIn)
+		case <-q.signalIn:
+		}
+	}
+	if q.signalOut != nil {
+		select {
+		default:
+			close(q.signalOut)
+		case <-q.signalOut:
+		}
+	}
+	q.size = newSize
+	q.signalIn = make(chan struct{}, newSize)
+	q.signalOut = make(chan struct{}, newSize)
+	q.evicted(discardedItems...)
+	return internal.Items(discardedItems)
+}
+
+func (q *queuePartitioned) GetSignalIn() <-chan struct{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.signalIn
+}
+
+func (q *queuePartitioned) GetSignalOut() <-chan struct{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.signalOut
+}
+
+func (q *queuePartitioned) Dequeue() (interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	wrappers := q.dequeue(1)
+	if len(wrappers) == 0 {
+		return nil, true
+	}
+	q.dequeued(wrappers...)
+	internal.SendSignal(q.signalOut)
+	return wrappers[0].Item, false
+}
+
+func (q *queuePartitioned) DequeueMultiple(n int) []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	wrappers := q.dequeue(n)
+	if len(wrappers) == 0 {
+		return nil
+	}
+	q.dequeued(wrappers...)
+	internal.SendSignal(q.signalOut)
+	return internal.Items(wrappers)
+}
+
+func (q *queuePartitioned) Flush() []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	wrappers := q.dequeue(q.length)
+	if len(wrappers) == 0 {
+		return nil
+	}
+	q.dequeued(wrappers...)
+	internal.SendSignal(q.signalOut)
+	return internal.Items(wrappers)
+}
+
+func (q *queuePartitioned) Enqueue(item interface{}) bool {
+	return q.PriorityEnqueue(item, priorityqueue.ItemPriority(item, q.priorityFunc))
+}
+
+func (q *queuePartitioned) EnqueueMultiple(items []interface{}) ([]interface{}, bool) {
+	return q.PriorityEnqueueMultiple(items, priorityqueue.ItemPriorities(items, q.priorityFunc)...)
+}
+
+func (q *queuePartitioned) EnqueueLossy(item interface{}) (interface{}, bool) {
+	return q.PriorityEnqueueLossy(item, priorityqueue.ItemPriority(item, q.priorityFunc))
+}
+
+func (q *queuePartitioned) PriorityEnqueue(item interface{}, priorities ...int) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	priority := priorityqueue.DefaultPriority
+	if len(priorities) > 0 {
+		priority = priorities[0]
+	}
+	if overflow := q.enqueue(partitionKey(item), item, priority); overflow {
+		return true
+	}
+	internal.SendSignal(q.signalIn)
+	return false
+}
+
+func (q *queuePartitioned) PriorityEnqueueMultiple(items []interface{}, priorities ...int) ([]interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	var itemEnqueued bool
+
+	defer func() {
+		if itemEnqueued {
+			internal.SendSignal(q.signalIn)
+		}
+	}()
+	priorities = expandPriorities(items, priorities)
+	for i, item := range items {
+		if q.enqueue(partitionKey(item), item, priorities[i]) {
+			return items[i:], true
+		}
+		itemEnqueued = true
+	}
+	return nil, false
+}
+
+func (q *queuePartitioned) PriorityEnqueueLossy(item interface{}, priorities ...int) (interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	priority := priorityqueue.DefaultPriority
+	if len(priorities) > 0 {
+		priority = priorities[0]
+	}
+	if q.length < q.size {
+		q.enqueue(partitionKey(item), item, priority)
+		internal.SendSignal(q.signalIn)
+		return nil, false
+	}
+
+	//KIM: the item that would be dequeued last is always at the tail of
+	// its partition, so it can be discarded without affecting the order
+	// of the items within its partition
+	keys := q.order(q.length)
+	tailKey := keys[len(keys)-1]
+	partition := q.partitions[tailKey]
+	tail := partition[len(partition)-1]
+	if priority < tail.Priority {
+		if q.observer != nil {
+			q.observer.Overflowed(priority)
+		}
+		return nil, true
+	}
+	partition[len(partition)-1] = nil
+	if partition = partition[:len(partition)-1]; len(partition) == 0 {
+		delete(q.partitions, tailKey)
+	} else {
+		q.partitions[tailKey] = partition
+	}
+	q.length--
+	q.evicted(tail)
+	q.enqueue(partitionKey(item), item, priority)
+	internal.SendSignal(q.signalIn)
+	return tail.Item, false
+}
+
+func (q *queuePartitioned) PartitionEnqueue(key string, item interface{}, priorities ...int) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	priority := priorityqueue.DefaultPriority
+	if len(priorities) > 0 {
+		priority = priorities[0]
+	}
+	if overflow := q.enqueue(key, item, priority); overflow {
+		return true
+	}
+	internal.SendSignal(q.signalIn)
+	return false
+}
+
+func (q *queuePartitioned) PartitionEnqueueMultiple(key string, items []interface{}, priorities ...int) ([]interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	var itemEnqueued bool
+
+	defer func() {
+		if itemEnqueued {
+			internal.SendSignal(q.signalIn)
+		}
+	}()
+	priorities = expandPriorities(items, priorities)
+	for i, item := range items {
+		if q.enqueue(key, item, priorities[i]) {
+			return items[i:], true
+		}
+		itemEnqueued = true
+	}
+	return nil, false
+}
+
+func (q *queuePartitioned) Length() int {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.length
+}
+
+func (q *queuePartitioned) Capacity() int {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.size
+}
+
+func (q *queuePartitioned) Peek() []interface{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return internal.Items(q.wrappers(q.length))
+}
+
+func (q *queuePartitioned) PeekHead() (interface{}, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	key, ok := q.head()
+	if !ok {
+		return nil, true
+	}
+	return q.partitions[key][0].Item, false
+}
+
+func (q *queuePartitioned) PeekFromHead(n int) []interface{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return internal.Items(q.wrappers(n))
+}
+
+func (q *queuePartitioned) PeekWrappers() []*priorityqueue.Wrapper {
+	q.RLock()
+	defer q.RUnlock()
+
+	wrappers := q.wrappers(q.length)
+	for i, wrapper := range wrappers {
+		w := *wrapper
+		wrappers[i] = &w
+	}
+	return wrappers
+}
+
+func (q *queuePartitioned) PeekWrappersFromHead(n int) []*priorityqueue.Wrapper {
+	q.RLock()
+	defer q.RUnlock()
+
+	if n > q.length {
+		n = q.length
+	}
+	if n < 0 {
+		n = 0
+	}
+	wrappers := q.wrappers(n)
+	for i, wrapper := range wrappers {
+		w := *wrapper
+		wrappers[i] = &w
+	}
+	return wrappers
+}
+
+func (q *queuePartitioned) PeekHeadWrapper() (*priorityqueue.Wrapper, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	key, ok := q.head()
+	if !ok {
+		return nil, true
+	}
+	wrapper := *q.partitions[key][0]
+	return &wrapper, false
+}
+
+// expandPriorities will ensure that there's a priority for each item, if
+// there isn't a priority for each item, the first priority (or the default
+// priority) is used for all items
+func expandPriorities(items []interface{}, priorities []int) []int {
+	if len(priorities) == len(items) {
+		return priorities
+	}
+	priority := priorityqueue.DefaultPriority
+	if len(priorities) > 0 {
+		priority = priorities[0]
+	}
+	priorities = make([]int, 0, len(items))
+	for range items {
+		priorities = append(priorities, priority)
+	}
+	return priorities
+}
+
+func (q *queuePartitioned) Snapshot(w io.Writer) error {
+	q.RLock()
+	defer q.RUnlock()
+
+	return priorityqueue.WriteSnapshot(w, q.wrappers(q.length))
+}
+
+func (q *queuePartitioned) Restore(r io.Reader) error {
+	wrappers, err := priorityqueue.ReadSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if len(wrappers) > q.size {
+		return priorityqueue.ErrSnapshotOverflow
+	}
+	q.evicted(q.dequeue(q.length)...)
+	for _, wrapper := range wrappers {
+		q.insert(wrapper)
+		if q.observer != nil {
+			q.observer.Enqueued(wrapper)
+		}
+	}
+	if len(wrappers) > 0 {
+		internal.SendSignal(q.signalIn)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package prioritypartitioned_test
+
+import (
+	"testing"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	goqueuepriorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	goqueueprioritypartitioned "github.com/antonio-alexander/go-queue-priority/partitioned"
+	finite "github.com/antonio-alexander/go-queue/finite"
+
+	"github.com/stretchr/testify/assert"
+
+	goqueuepriorityfinite_tests "github.com/antonio-alexander/go-queue-priority/finite/tests"
+	finite_tests "github.com/antonio-alexander/go-queue/finite/tests"
+	goqueue_tests "github.com/antonio-alexander/go-queue/tests"
+)
+
+const (
+	mustTimeout time.Duration = time.Second
+	mustRate    time.Duration = time.Millisecond
+)
+
+func TestPartitionedFiniteQueue(t *testing.T) {
+	t.Run("Test Enqueue", finite_tests.TestEnqueue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Enqueue Multiple", finite_tests.TestEnqueueMultiple(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Enqueue Event", finite_tests.TestEnqueueEvent(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Event
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+
+	t.Run("Test Resize", finite_tests.TestResize(t, func(size int) interface {
+		finite.Capacity
+		goqueue.Enqueuer
+		goqueue.Owner
+		finite.Resizer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	//REVIEW: how to fix this functionality?
+	// t.Run("Test Enqueue Lossy", finite_tests.TestEnqueueLossy(t, func(size int) interface {
+	// 	goqueue.Owner
+	// 	finite.EnqueueLossy
+	// } {
+	// 	return goqueueprioritypartitioned.New(size)
+	// }))
+	t.Run("Test Capacity", finite_tests.TestCapacity(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		finite.Capacity
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("Test Dequeue", goqueue_tests.TestDequeue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Dequeue Event", goqueue_tests.TestDequeueEvent(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Dequeuer
+		goqueue.Enqueuer
+		goqueue.Event
+		goqueue.Owner
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Dequeue Multiple", goqueue_tests.TestDequeueMultiple(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Flush", goqueue_tests.TestFlush(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Peek", goqueue_tests.TestPeek(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Peeker
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Peek From Head", goqueue_tests.TestPeekFromHead(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Peeker
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Length", goqueue_tests.TestLength(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Length
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Garbage Collect", goqueue_tests.TestGarbageCollect(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.GarbageCollecter
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	//
+	t.Run("Test Queue", goqueue_tests.TestQueue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Asynchronous", goqueue_tests.TestAsync(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+}
+
+func TestPriorityPartitionedQueue(t *testing.T) {
+	t.Run("Test Priority Enqueue", goqueuepriorityfinite_tests.TestPriorityEnqueue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueuepriority.PriorityEnqueuer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Priority Enqueue Event", goqueuepriorityfinite_tests.TestPriorityEnqueueEvent(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueue.Event
+		goqueuepriority.PriorityEnqueuer
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Priority Enqueue Lossy", goqueuepriorityfinite_tests.TestPriorityEnqueueLossy(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueuepriority.PriorityEnqueuer
+		goqueuepriorityfinite.PriorityEnqueueLossy
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Priority Enqueue Lossy Event", goqueuepriorityfinite_tests.TestPriorityEnqueueLossyEvent(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueue.Event
+		goqueuepriority.PriorityEnqueuer
+		goqueuepriorityfinite.PriorityEnqueueLossy
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+	t.Run("Test Snapshot", goqueuepriorityfinite_tests.TestSnapshot(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueuepriority.PriorityEnqueuer
+		goqueuepriority.WrapperPeeker
+		goqueuepriority.Snapshotter
+	} {
+		return goqueueprioritypartitioned.New(size)
+	}))
+}
+
+func TestPartitionEnqueue(t *testing.T) {
+	q := goqueueprioritypartitioned.New(10)
+	defer q.Close()
+
+	//the second item for device "a" has a higher priority, but it
+	// can't be dequeued before the first item for device "a"
+	assert.False(t, q.PartitionEnqueue("a", goqueue.Example{Int: 1}, 1))
+	assert.False(t, q.PartitionEnqueue("b", goqueue.Example{Int: 2}, 2))
+	assert.False(t, q.PartitionEnqueue("a", goqueue.Example{Int: 3}, 5))
+	assert.False(t, q.PartitionEnqueue("c", goqueue.Example{Int: 4}, 3))
+	itemsRemaining, overflow := q.PartitionEnqueueMultiple("b", []interface{}{
+		goqueue.Example{Int: 5},
+		goqueue.Example{Int: 6},
+	}, 4, 0)
+	assert.False(t, overflow)
+	assert.Nil(t, itemsRemaining)
+	expected := []interface{}{
+		goqueue.Example{Int: 4},
+		goqueue.Example{Int: 2},
+		goqueue.Example{Int: 5},
+		goqueue.Example{Int: 1},
+		goqueue.Example{Int: 3},
+		goqueue.Example{Int: 6},
+	}
+	assert.Equal(t, expected, q.Peek())
+	item, underflow := q.PeekHead()
+	assert.False(t, underflow)
+	assert.Equal(t, expected[0], item)
+	assert.Equal(t, expected, q.Flush())
+}
+
+func TestPartitionEnqueueLossy(t *testing.T) {
+	q := goqueueprioritypartitioned.New(2)
+	defer q.Close()
+
+	assert.False(t, q.PartitionEnqueue("a", goqueue.Example{Int: 1}, 2))
+	assert.False(t, q.PartitionEnqueue("a", goqueue.Example{Int: 2}, 1))
+
+	//an item with a lower priority shouldn't be enqueued
+	item, overflow := q.PriorityEnqueueLossy(goqueue.Example{Int: 3}, 0)
+	assert.True(t, overflow)
+	assert.Nil(t, item)
+
+	//an item with a greater priority should discard the tail
+	item, overflow = q.PriorityEnqueueLossy(goqueue.Example{Int: 4}, 3)
+	assert.False(t, overflow)
+	assert.Equal(t, goqueue.Example{Int: 2}, item)
+	assert.Equal(t, []interface{}{
+		goqueue.Example{Int: 4},
+		goqueue.Example{Int: 1},
+	}, q.Flush())
+}
+
+// TestUnpartitioned is meant to confirm that items that aren't partitioned
+// are dequeued by priority (and in the order they were enqueued within a
+// priority) alongside partitioned items
+func TestUnpartitioned(t *testing.T) {
+	var expected []interface{}
+
+	q := goqueueprioritypartitioned.New(101)
+	defer q.Close()
+
+	assert.False(t, q.PartitionEnqueue("a", goqueue.Example{Int: -1}, 1))
+	for i := 0; i < 100; i++ {
+		assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: i}, i%3))
+	}
+	for _, priority := range []int{2, 1, 0} {
+		if priority == 1 {
+			expected = append(expected, goqueue.Example{Int: -1})
+		}
+		for i := 0; i < 100; i++ {
+			if i%3 == priority {
+				expected = append(expected, goqueue.Example{Int: i})
+			}
+		}
+	}
+	assert.Equal(t, expected, q.Flush())
+}
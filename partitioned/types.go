@@ -0,0 +1,22 @@
+package prioritypartitioned
+
+// Partitioner can be implemented by items to provide their partition
+// key when they're enqueued without one, items that are enqueued without
+// a partition key aren't partitioned and compete only on priority
+type Partitioner interface {
+	PartitionKey() string
+}
+
+// PartitionEnqueuer describes an interface for enqueueing items into
+// a given partition with priority, an empty partition key is the same
+// as enqueueing an item without a partition
+type PartitionEnqueuer interface {
+	//PartitionEnqueue can be used to enqueue a single item into
+	// the partition with an optional priority
+	PartitionEnqueue(key string, item interface{}, priority ...int) (overflow bool)
+
+	//PartitionEnqueueMultiple can be used to enqueue zero or more items
+	// into the partition with an optional priority; a single priority can
+	// be provided OR a priority for each item can be provided
+	PartitionEnqueueMultiple(key string, items []interface{}, priority ...int) (itemsRemaining []interface{}, overflow bool)
+}
@@ -1,11 +1,17 @@
 package priority_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"io"
 	"sort"
 	"testing"
 	"time"
 
+	goqueue "github.com/antonio-alexander/go-queue"
 	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	goqueuepriorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -111,3 +117,247 @@ func TestSortByPriorityAndEnqueuedAt(t *testing.T) {
 		assert.Equal(t, c.oWrappers, wrappers, casef, cDesc)
 	}
 }
+
+// TestRetryPolicy is meant to confirm that items that are retried
+// maintain their attempts, have their priority adjusted and are sent
+// to the dead letter destination once their attempts are exhausted
+func TestRetryPolicy(t *testing.T) {
+	var deadLetter *goqueuepriority.Wrapper
+
+	q := goqueuepriorityfinite.New(2)
+	defer q.Close()
+	policy := goqueuepriority.RetryPolicy{
+		MaxAttempts:        3,
+		Backoff:            time.Millisecond,
+		PriorityAdjustment: -1,
+		DeadLetter: func(wrapper *goqueuepriority.Wrapper) {
+			deadLetter = wrapper
+		},
+	}
+	assert.False(t, q.PriorityEnqueue(goqueue.Example{Int: 1}, 5))
+	wrapper, underflow := q.DequeueWrapper()
+	assert.False(t, underflow)
+	for attempt := 1; attempt < 3; attempt++ {
+		deadLettered, overflow := policy.Retry(q, wrapper, nil)
+		assert.False(t, deadLettered)
+		assert.False(t, overflow)
+		head, underflow := q.PeekHeadWrapper()
+		if assert.False(t, underflow) {
+			assert.Equal(t, goqueue.Example{Int: 1}, head.Item)
+			assert.Equal(t, attempt, head.Attempts)
+			assert.Equal(t, 5-attempt, head.Priority)
+		}
+		wrapper, underflow = q.DequeueWrapper()
+		assert.False(t, underflow)
+	}
+	deadLettered, overflow := policy.Retry(q, wrapper, nil)
+	assert.True(t, deadLettered)
+	assert.False(t, overflow)
+	if assert.NotNil(t, deadLetter) {
+		assert.Equal(t, goqueue.Example{Int: 1}, deadLetter.Item)
+		assert.Equal(t, 3, deadLetter.Attempts)
+	}
+
+	//validate that a max attempts of zero uses the default
+	policy = goqueuepriority.RetryPolicy{PriorityAdjustment: 1}
+	deadLettered, _ = policy.Retry(q, &goqueuepriority.Wrapper{
+		Attempts: goqueuepriority.DefaultMaxAttempts - 1,
+	}, nil)
+	assert.True(t, deadLettered)
+	assert.Equal(t, 0, q.Length())
+}
+
+// TestChannel is meant to confirm that items can be pumped from a channel
+// into a queue and out of the queue into a channel in priority order
+func TestChannel(t *testing.T) {
+	const timeout = time.Second
+
+	q := goqueuepriorityfinite.New(2)
+	ch := make(chan goqueuepriority.Prioritized, 3)
+	ch <- goqueuepriority.Prioritized{Item: goqueue.Example{Int: 1}, Priority: 1}
+	ch <- goqueuepriority.Prioritized{Item: goqueue.Example{Int: 2}, Priority: 2}
+	ch <- goqueuepriority.Prioritized{Item: goqueue.Example{Int: 3}, Priority: 3}
+	close(ch)
+	stopped := goqueuepriority.Inbound(q, ch)
+
+	//the queue is full, so the third item should be held back
+	assert.Eventually(t, func() bool { return q.Length() == 2 }, timeout, time.Millisecond)
+	assert.Len(t, ch, 0)
+	item, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, goqueue.Example{Int: 2}, item)
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		assert.Fail(t, "timed out waiting for inbound to stop")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := goqueuepriority.Outbound(ctx, q)
+	assert.Equal(t, goqueue.Example{Int: 3}, <-out)
+	assert.Equal(t, goqueue.Example{Int: 1}, <-out)
+
+	//closing the queue should close the outbound channel
+	q.Close()
+	select {
+	case _, ok := <-out:
+		assert.False(t, ok)
+	case <-time.After(timeout):
+		assert.Fail(t, "timed out waiting for outbound to close")
+	}
+}
+
+// TestMerge is meant to confirm that a merged view of multiple queues
+// dequeues the item with the highest priority across all queues and
+// that items with the same priority are dequeued in the order they
+// were enqueued
+func TestMerge(t *testing.T) {
+	const timeout = time.Second
+
+	q1, q2 := goqueuepriorityfinite.New(3), goqueuepriorityfinite.New(3)
+	q := goqueuepriority.Merge(q1, q2)
+	assert.False(t, q1.PriorityEnqueue(goqueue.Example{Int: 1}, 1))
+	assert.False(t, q2.PriorityEnqueue(goqueue.Example{Int: 2}, 2))
+	assert.False(t, q1.PriorityEnqueue(goqueue.Example{Int: 3}, 2))
+	assert.False(t, q2.PriorityEnqueue(goqueue.Example{Int: 4}, 1))
+	select {
+	case <-q.GetSignalIn():
+	case <-time.After(timeout):
+		assert.Fail(t, "timed out waiting for signal")
+	}
+	expected := []interface{}{
+		goqueue.Example{Int: 2},
+		goqueue.Example{Int: 3},
+		goqueue.Example{Int: 1},
+		goqueue.Example{Int: 4},
+	}
+	assert.Equal(t, expected, q.Peek())
+	assert.Equal(t, expected[:2], q.PeekFromHead(2))
+	item, underflow := q.PeekHead()
+	assert.False(t, underflow)
+	assert.Equal(t, expected[0], item)
+	item, underflow = q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, expected[0], item)
+	assert.Equal(t, 2, q1.Length())
+	assert.Equal(t, 1, q2.Length())
+	assert.Equal(t, expected[1:3], q.DequeueMultiple(2))
+	assert.Equal(t, expected[3:], q.Flush())
+	_, underflow = q.Dequeue()
+	assert.True(t, underflow)
+
+	//once all sources are closed, the signals should be cleared
+	q1.Close()
+	q2.Close()
+	assert.Eventually(t, func() bool {
+		return q.GetSignalIn() == nil && q.GetSignalOut() == nil
+	}, timeout, time.Millisecond)
+}
+
+type codecItem struct {
+	Name  string
+	Value int
+}
+
+func TestCodec(t *testing.T) {
+	goqueuepriority.RegisterType("priority_test.codecItem", codecItem{})
+	gob.Register(codecItem{})
+
+	wrapper := &goqueuepriority.Wrapper{
+		Priority:   -3,
+		Key:        []int{2, -1},
+		EnqueuedAt: time.Now().UnixNano(),
+		Attempts:   2,
+		Tenant:     "tenant",
+		Partition:  "partition",
+		Item:       codecItem{Name: "item", Value: 1},
+	}
+	for cName, codec := range map[string]goqueuepriority.Codec{
+		"json": goqueuepriority.JSONCodec{},
+		"gob":  goqueuepriority.GobCodec{},
+	} {
+		t.Run(cName, func(t *testing.T) {
+			//validate that items retain their type
+			bytes, err := codec.Marshal(wrapper.Item)
+			assert.Nil(t, err)
+			item, err := codec.Unmarshal(bytes)
+			assert.Nil(t, err)
+			assert.Equal(t, wrapper.Item, item)
+
+			//validate that the envelope round trips and that
+			// truncated envelopes or unknown versions are rejected
+			envelope, err := goqueuepriority.MarshalWrapper(codec, wrapper)
+			assert.Nil(t, err)
+			decoded, err := goqueuepriority.UnmarshalWrapper(codec, envelope)
+			assert.Nil(t, err)
+			assert.Equal(t, wrapper, decoded)
+			_, err = goqueuepriority.UnmarshalWrapper(codec, envelope[:len(envelope)-1])
+			assert.ErrorIs(t, err, goqueuepriority.ErrEnvelope)
+			_, err = goqueuepriority.UnmarshalWrapper(codec, append([]byte{2}, envelope[1:]...))
+			assert.ErrorIs(t, err, goqueuepriority.ErrEnvelope)
+		})
+	}
+
+	//validate that wrappers can be written to and read from a stream
+	buffer := &bytes.Buffer{}
+	codec := goqueuepriority.JSONCodec{}
+	for i := 0; i < 3; i++ {
+		err := goqueuepriority.WriteWrapper(buffer, codec, &goqueuepriority.Wrapper{
+			Priority: i,
+			Item:     codecItem{Value: i},
+		})
+		assert.Nil(t, err)
+	}
+	buffer.Truncate(buffer.Len() - 1)
+	for i := 0; i < 2; i++ {
+		wrapper, err := goqueuepriority.ReadWrapper(buffer, codec)
+		assert.Nil(t, err)
+		assert.Equal(t, codecItem{Value: i}, wrapper.Item)
+	}
+	_, err := goqueuepriority.ReadWrapper(buffer, codec)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, err = goqueuepriority.ReadWrapper(buffer, codec)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// TestCompare is meant to confirm that keys are compared lexicographically
+// after the priority and that missing levels are treated as the default
+// priority
+func TestCompare(t *testing.T) {
+	for cDesc, c := range map[string]struct {
+		a, b     *goqueuepriority.Wrapper
+		expected int
+	}{
+		"priority": {
+			a:        &goqueuepriority.Wrapper{Priority: 2, Key: []int{-1}},
+			b:        &goqueuepriority.Wrapper{Priority: 1, Key: []int{5}},
+			expected: 1,
+		},
+		"key": {
+			a:        &goqueuepriority.Wrapper{Priority: 1, Key: []int{2, 1}},
+			b:        &goqueuepriority.Wrapper{Priority: 1, Key: []int{2, 3}},
+			expected: -1,
+		},
+		"missing_level": {
+			a:        &goqueuepriority.Wrapper{Priority: 1},
+			b:        &goqueuepriority.Wrapper{Priority: 1, Key: []int{0, 0}},
+			expected: 0,
+		},
+		"negative_level": {
+			a:        &goqueuepriority.Wrapper{Priority: 1},
+			b:        &goqueuepriority.Wrapper{Priority: 1, Key: []int{-1}},
+			expected: 1,
+		},
+	} {
+		t.Run(cDesc, func(t *testing.T) {
+			assert.Equal(t, c.expected, goqueuepriority.Compare(c.a, c.b))
+			assert.Equal(t, -c.expected, goqueuepriority.Compare(c.b, c.a))
+		})
+	}
+	priority, key := goqueuepriority.SplitKey(nil)
+	assert.Equal(t, goqueuepriority.DefaultPriority, priority)
+	assert.Nil(t, key)
+	priority, key = goqueuepriority.SplitKey([]int{3, 2, 1})
+	assert.Equal(t, 3, priority)
+	assert.Equal(t, []int{2, 1}, key)
+}
@@ -0,0 +1,91 @@
+package priority
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var typeRegistry = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}{
+	byName: make(map[string]reflect.Type),
+	byType: make(map[reflect.Type]string),
+}
+
+// RegisterType can be used to register the concrete type of value with the given
+// name such that items of that type can be encoded and decoded without losing their
+// type (e.g. decoding to map[string]interface{}); pointers and values are registered
+// separately. Like gob.Register, this will panic if the name or type has already
+// been registered with a different type or name
+func RegisterType(name string, value interface{}) {
+	typeRegistry.Lock()
+	defer typeRegistry.Unlock()
+
+	t := reflect.TypeOf(value)
+	if existing, ok := typeRegistry.byName[name]; ok && existing != t {
+		panic(fmt.Sprintf("priority: registering duplicate types for %q: %s != %s", name, existing, t))
+	}
+	if existing, ok := typeRegistry.byType[t]; ok && existing != name {
+		panic(fmt.Sprintf("priority: registering duplicate names for %s: %q != %q", t, existing, name))
+	}
+	typeRegistry.byName[name], typeRegistry.byType[t] = t, name
+}
+
+// TypeName will return the name the type of the item has been registered with,
+// it will return false if the type of the item hasn't been registered
+func TypeName(item interface{}) (string, bool) {
+	typeRegistry.RLock()
+	defer typeRegistry.RUnlock()
+
+	name, ok := typeRegistry.byType[reflect.TypeOf(item)]
+	return name, ok
+}
+
+// marshalItem will encode the item as JSON along with the name of its type
+// (if registered)
+func marshalItem(item interface{}) (string, json.RawMessage, error) {
+	name, _ := TypeName(item)
+	bytes, err := json.Marshal(item)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, bytes, nil
+}
+
+// unmarshalItem will decode the JSON into the type registered with the given
+// name, if no name is provided it will be decoded into an empty interface
+func unmarshalItem(name string, bytes json.RawMessage) (interface{}, error) {
+	var item interface{}
+
+	if name == "" {
+		if len(bytes) == 0 {
+			return nil, nil
+		}
+		if err := json.Unmarshal(bytes, &item); err != nil {
+			return nil, err
+		}
+		return item, nil
+	}
+	typeRegistry.RLock()
+	t, ok := typeRegistry.byName[name]
+	typeRegistry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("priority: type %q not registered", name)
+	}
+	if t.Kind() == reflect.Ptr {
+		value := reflect.New(t.Elem())
+		if err := json.Unmarshal(bytes, value.Interface()); err != nil {
+			return nil, err
+		}
+		return value.Interface(), nil
+	}
+	value := reflect.New(t)
+	if err := json.Unmarshal(bytes, value.Interface()); err != nil {
+		return nil, err
+	}
+	return value.Elem().Interface(), nil
+}
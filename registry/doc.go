@@ -0,0 +1,11 @@
+// Copyright 2023 antonio-alexander. All rights reserved.
+// Use of this source code is governed by an MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package registry provides a registry that creates and looks up finite priority
+queues by name; every queue created by the registry has a metrics collector
+and is registered with the debug handler so each queue within a process is
+discoverable, and all of the queues can be closed on shutdown
+*/
+package registry
@@ -0,0 +1,215 @@
+package registry
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	debug "github.com/antonio-alexander/go-queue-priority/debug"
+	priorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	metrics "github.com/antonio-alexander/go-queue-priority/metrics"
+)
+
+// entry is a queue along with its collector
+type entry struct {
+	queue     Queue
+	collector metrics.Collector
+}
+
+// observers will notify each of its observers
+type observers []goqueuepriority.Observer
+
+func (o observers) Enqueued(wrapper *goqueuepriority.Wrapper) {
+	for _, observer := range o {
+		observer.Enqueued(wrapper)
+	}
+}
+
+func (o observers) Dequeued(wrapper *goqueuepriority.Wrapper) {
+	for _, observer := range o {
+		observer.Dequeued(wrapper)
+	}
+}
+
+func (o observers) Overflowed(priority int) {
+	for _, observer := range o {
+		observer.Overflowed(priority)
+	}
+}
+
+func (o observers) Rejected(priority int) {
+	for _, observer := range o {
+		if observer, ok := observer.(goqueuepriority.RejectionObserver); ok {
+			observer.Rejected(priority)
+			continue
+		}
+		observer.Overflowed(priority)
+	}
+}
+
+func (o observers) Evicted(wrapper *goqueuepriority.Wrapper) {
+	for _, observer := range o {
+		observer.Evicted(wrapper)
+	}
+}
+
+func (o observers) Reprioritized(wrapper *goqueuepriority.Wrapper, from int) {
+	for _, observer := range o {
+		goqueuepriority.NotifyReprioritized(observer, wrapper, from)
+	}
+}
+
+type registry struct {
+	sync.RWMutex
+	config  Configuration
+	entries map[string]*entry
+	closed  bool
+}
+
+// New can be used to create a registry, a Configuration can optionally
+// be provided as a parameter
+func New(parameters ...interface{}) Registry {
+	r := &registry{entries: make(map[string]*entry)}
+	for _, parameter := range parameters {
+		switch p := parameter.(type) {
+		case Configuration:
+			r.config = p
+		case *Configuration:
+			r.config = *p
+		}
+	}
+	return r
+}
+
+// create will create the queue, any observers within the parameters are
+// notified along with the queue's collector which uses the queue's clock
+func (r *registry) create(name string, size int, parameters []interface{}) Queue {
+	var others observers
+
+	m := []interface{}{r.config.Buckets}
+	p := make([]interface{}, 0, len(parameters)+1)
+	for _, parameter := range parameters {
+		switch parameter := parameter.(type) {
+		case priorityfinite.Clock:
+			m = append(m, metrics.Clock(parameter))
+		case func() time.Time:
+			m = append(m, metrics.Clock(parameter))
+		case goqueuepriority.Observer:
+			others = append(others, parameter)
+			continue
+		}
+		p = append(p, parameter)
+	}
+	collector := metrics.New(m...)
+	if len(others) == 0 {
+		p = append(p, collector)
+	} else {
+		p = append(p, append(observers{collector}, others...))
+	}
+	queue := priorityfinite.New(size, p...)
+	r.entries[name] = &entry{queue: queue, collector: collector}
+	if !r.config.DisableDebug {
+		debug.Register(r.config.DebugPrefix+name, queue)
+	}
+	return queue
+}
+
+// remove will remove the queue, unregister it and close it
+func (r *registry) remove(name string) []interface{} {
+	e, ok := r.entries[name]
+	if !ok {
+		return nil
+	}
+	delete(r.entries, name)
+	if !r.config.DisableDebug {
+		debug.Unregister(r.config.DebugPrefix + name)
+	}
+	return e.queue.Close()
+}
+
+func (r *registry) Create(name string, size int, parameters ...interface{}) (Queue, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.closed {
+		return nil, ErrClosed
+	}
+	if _, ok := r.entries[name]; ok {
+		return nil, ErrExists
+	}
+	return r.create(name, size, parameters), nil
+}
+
+func (r *registry) Get(name string) (Queue, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	e, ok := r.entries[name]
+	if !ok {
+		return nil, false
+	}
+	return e.queue, true
+}
+
+func (r *registry) GetOrCreate(name string, size int, parameters ...interface{}) (Queue, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if e, ok := r.entries[name]; ok {
+		return e.queue, nil
+	}
+	if r.closed {
+		return nil, ErrClosed
+	}
+	return r.create(name, size, parameters), nil
+}
+
+func (r *registry) Names() []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *registry) Remove(name string) []interface{} {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.remove(name)
+}
+
+func (r *registry) Collectors() map[string]metrics.Collector {
+	r.RLock()
+	defer r.RUnlock()
+
+	collectors := make(map[string]metrics.Collector, len(r.entries))
+	for name, e := range r.entries {
+		collectors[name] = e.collector
+	}
+	return collectors
+}
+
+func (r *registry) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		metrics.Handler(r.Collectors()).ServeHTTP(w, req)
+	})
+}
+
+func (r *registry) Close() map[string][]interface{} {
+	r.Lock()
+	defer r.Unlock()
+
+	remainingItems := make(map[string][]interface{}, len(r.entries))
+	for name := range r.entries {
+		remainingItems[name] = r.remove(name)
+	}
+	r.closed = true
+	return remainingItems
+}
@@ -0,0 +1,110 @@
+package registry_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	debug "github.com/antonio-alexander/go-queue-priority/debug"
+	goqueuepriorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	registry "github.com/antonio-alexander/go-queue-priority/registry"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type observer struct {
+	enqueued   int
+	overflowed int
+}
+
+func (o *observer) Enqueued(*goqueuepriority.Wrapper) { o.enqueued++ }
+func (o *observer) Dequeued(*goqueuepriority.Wrapper) {}
+func (o *observer) Overflowed(int)                    { o.overflowed++ }
+func (o *observer) Evicted(*goqueuepriority.Wrapper)  {}
+
+func TestRegistry(t *testing.T) {
+	o := &observer{}
+	r := registry.New(registry.Configuration{DebugPrefix: "test/"})
+
+	//validate that queues can be created and looked up
+	jobs, err := r.Create("jobs", 2, o, goqueuepriorityfinite.TenantCapacity(1))
+	assert.Nil(t, err)
+	_, err = r.Create("jobs", 2)
+	assert.Equal(t, registry.ErrExists, err)
+	queue, err := r.GetOrCreate("jobs", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, jobs, queue)
+	assert.Equal(t, 2, queue.Capacity())
+	_, err = r.GetOrCreate("events", 5, o, goqueuepriorityfinite.AdmissionFunc(func(priority, length, capacity int) bool {
+		return priority > 0
+	}))
+	assert.Nil(t, err)
+	queue, ok := r.Get("events")
+	assert.True(t, ok)
+	assert.Equal(t, 5, queue.Capacity())
+	_, ok = r.Get("missing")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"events", "jobs"}, r.Names())
+
+	//validate that the parameters are used and that the collector and
+	// provided observer are notified
+	assert.False(t, jobs.TenantEnqueue("tenant", "a", 1))
+	assert.True(t, jobs.TenantEnqueue("tenant", "b", 1))
+	assert.Equal(t, 1, o.enqueued)
+	assert.Equal(t, 1, o.overflowed)
+	collectors := r.Collectors()
+	if assert.Contains(t, collectors, "jobs") {
+		m := collectors["jobs"].Snapshot()
+		if assert.Len(t, m, 1) {
+			assert.Equal(t, uint64(1), m[0].Enqueued)
+			assert.Equal(t, uint64(1), m[0].Overflowed)
+		}
+	}
+
+	//validate that rejections are counted by the collector and reported
+	// as overflows to observers that don't implement RejectionObserver
+	assert.True(t, queue.PriorityEnqueue("c", 0))
+	assert.Equal(t, 2, o.overflowed)
+	if m := r.Collectors()["events"].Snapshot(); assert.Len(t, m, 1) {
+		assert.Equal(t, uint64(1), m[0].Rejected)
+		assert.Equal(t, uint64(0), m[0].Overflowed)
+	}
+	recorder := httptest.NewRecorder()
+	r.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.True(t, strings.Contains(recorder.Body.String(), `queue="jobs"`))
+
+	//validate that observers are notified when items are reprioritized,
+	// the provided observer is notified that the item was enqueued again
+	assert.Equal(t, 1, jobs.Reprioritize(1, 2))
+	assert.Equal(t, 2, o.enqueued)
+	for _, m := range r.Collectors()["jobs"].Snapshot() {
+		assert.Equal(t, map[int]int64{1: 0, 2: 1}[m.Priority], m.Depth, m.Priority)
+	}
+
+	//validate that the queues are registered with the debug handler
+	var index debug.Index
+	recorder = httptest.NewRecorder()
+	debug.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/queues", nil))
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&index))
+	if assert.Len(t, index.Queues, 2) {
+		assert.Equal(t, "test/events", index.Queues[0].Name)
+		assert.Equal(t, "test/jobs", index.Queues[1].Name)
+	}
+
+	//validate that queues can be removed and that all queues are
+	// closed when the registry is closed
+	assert.Nil(t, r.Remove("events"))
+	assert.Equal(t, []string{"jobs"}, r.Names())
+	assert.Equal(t, map[string][]interface{}{"jobs": {"a"}}, r.Close())
+	assert.Empty(t, r.Names())
+	_, err = r.GetOrCreate("jobs", 1)
+	assert.Equal(t, registry.ErrClosed, err)
+	index = debug.Index{}
+	recorder = httptest.NewRecorder()
+	debug.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/queues", nil))
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&index))
+	assert.Empty(t, index.Queues)
+}
@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	priorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	metrics "github.com/antonio-alexander/go-queue-priority/metrics"
+	finite "github.com/antonio-alexander/go-queue/finite"
+)
+
+// ErrExists is returned when creating a queue with a name that's
+// already registered
+var ErrExists = errors.New("queue already exists")
+
+// ErrClosed is returned when creating a queue once the registry
+// has been closed
+var ErrClosed = errors.New("registry is closed")
+
+// Queue describes the functionality of the queues created by the
+// registry
+type Queue interface {
+	goqueue.Owner
+	goqueue.GarbageCollecter
+	goqueue.Length
+	goqueue.Event
+	goqueue.Peeker
+	goqueue.Dequeuer
+	goqueue.Enqueuer
+	finite.EnqueueLossy
+	finite.Resizer
+	finite.Capacity
+	goqueuepriority.PriorityEnqueuer
+	goqueuepriority.TenantEnqueuer
+	goqueuepriority.WrapperPeeker
+	goqueuepriority.Snapshotter
+	goqueuepriority.Reprioritizer
+	priorityfinite.PriorityEnqueueLossy
+}
+
+// Registry describes the functionality of a registry of queues
+type Registry interface {
+	//Create will create a queue with the given name, size and parameters
+	// (see priorityfinite.New), it will return ErrExists if a queue with
+	// the same name already exists
+	Create(name string, size int, parameters ...interface{}) (queue Queue, err error)
+
+	//Get will return the queue with the given name, ok will be false
+	// if the queue doesn't exist
+	Get(name string) (queue Queue, ok bool)
+
+	//GetOrCreate will return the queue with the given name, creating
+	// it if it doesn't exist
+	GetOrCreate(name string, size int, parameters ...interface{}) (queue Queue, err error)
+
+	//Names will return the names of every queue sorted
+	Names() (names []string)
+
+	//Remove will close and remove the queue with the given name, it
+	// returns the items that remained in the queue
+	Remove(name string) (remainingItems []interface{})
+
+	//Collectors will return the metrics collector of every queue
+	Collectors() (collectors map[string]metrics.Collector)
+
+	//MetricsHandler will return a handler that serves the metrics of
+	// every queue in the Prometheus text format
+	MetricsHandler() http.Handler
+
+	//Close will close and remove every queue, it returns the items
+	// that remained in each queue
+	Close() (remainingItems map[string][]interface{})
+}
+
+// Configuration can be provided to New to configure the registry
+type Configuration struct {
+	//Buckets are the buckets of the time-in-queue histogram of each
+	// queue's collector, metrics.DefaultBuckets are used if empty
+	Buckets []time.Duration
+
+	//DebugPrefix is prepended to the name of each queue when registered
+	// with the debug handler, to avoid conflicts between registries
+	DebugPrefix string
+
+	//DisableDebug will prevent queues from being registered with the
+	// debug handler
+	DisableDebug bool
+}
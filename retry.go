@@ -0,0 +1,113 @@
+package priority
+
+import (
+	"time"
+)
+
+// DefaultRetryMultiplier is the multiplier used to increase the backoff
+// between attempts if one isn't provided
+const DefaultRetryMultiplier float64 = 2
+
+// DefaultMaxAttempts is the number of attempts that can be made before an
+// item is sent to the dead letter destination if one isn't provided
+const DefaultMaxAttempts int = 3
+
+// RetryPolicy describes how items that have failed should be re-enqueued;
+// items are re-enqueued along with their wrapper (see WrapperEnqueuer) so
+// the number of attempts is maintained between retries
+type RetryPolicy struct {
+	//MaxAttempts is the number of attempts that can be made before the
+	// item is sent to the dead letter destination, if zero (or less)
+	// DefaultMaxAttempts is used
+	MaxAttempts int
+
+	//Backoff is the time to wait before the first retry, each successive
+	// retry multiplies the backoff by Multiplier up to MaxBackoff (if
+	// greater than zero)
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Multiplier float64
+
+	//PriorityAdjustment is added to the priority of the item for each
+	// attempt, a negative value will decay the priority while a positive
+	// value will boost it
+	PriorityAdjustment int
+
+	//Rate is the rate at which an enqueue will be attempted if the
+	// queue is full (see MustPriorityEnqueue)
+	Rate time.Duration
+
+	//DeadLetter is executed with the wrapper of any item that has
+	// exhausted its attempts
+	DeadLetter func(wrapper *Wrapper)
+}
+
+// backoff will return the time to wait before the given attempt
+func (r RetryPolicy) backoff(attempts int) time.Duration {
+	multiplier := r.Multiplier
+	if multiplier <= 0 {
+		multiplier = DefaultRetryMultiplier
+	}
+	backoff := float64(r.Backoff)
+	for i := 1; i < attempts; i++ {
+		backoff *= multiplier
+		if r.MaxBackoff > 0 && backoff >= float64(r.MaxBackoff) {
+			return r.MaxBackoff
+		}
+	}
+	return time.Duration(backoff)
+}
+
+// Retry can be used to re-enqueue the wrapper of an item (e.g. dequeued using
+// WrapperDequeuer) whose processing has failed; the wrapper is re-enqueued with
+// its priority plus the priority adjustment and the number of attempts is
+// incremented. Retry will block for the backoff and until the item is enqueued
+// (or the done channel is closed), if the item has exhausted its attempts it
+// will be provided to the dead letter destination and deadLetter will be true
+func (r RetryPolicy) Retry(queue WrapperEnqueuer, wrapper *Wrapper, done <-chan struct{}) (deadLetter bool, overflow bool) {
+	retry := *wrapper
+	retry.Attempts++
+	maxAttempts := r.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if retry.Attempts >= maxAttempts {
+		if r.DeadLetter != nil {
+			r.DeadLetter(&retry)
+		}
+		return true, false
+	}
+	if backoff := r.backoff(retry.Attempts); backoff > 0 {
+		tBackoff := time.NewTimer(backoff)
+		defer tBackoff.Stop()
+		select {
+		case <-done:
+		case <-tBackoff.C:
+		}
+	}
+	retry.Priority += r.PriorityAdjustment
+	rate := r.Rate
+	if rate <= 0 {
+		rate = time.Millisecond
+	}
+	return false, MustPriorityEnqueue(wrapperEnqueuer{queue}, &retry, retry.Priority, done, rate)
+}
+
+// wrapperEnqueuer is used to enqueue wrappers as if they were items
+// (e.g. using MustPriorityEnqueue), the priority of the wrapper is used
+type wrapperEnqueuer struct {
+	WrapperEnqueuer
+}
+
+func (w wrapperEnqueuer) PriorityEnqueue(item interface{}, _ ...int) bool {
+	return w.EnqueueWrapper(item.(*Wrapper))
+}
+
+func (w wrapperEnqueuer) PriorityEnqueueMultiple(items []interface{}, _ ...int) ([]interface{}, bool) {
+	for i, item := range items {
+		if overflow := w.EnqueueWrapper(item.(*Wrapper)); overflow {
+			return items[i:], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,10 @@
+// Copyright 2023 antonio-alexander. All rights reserved.
+// Use of this source code is governed by an MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package server provides an http handler that exposes named priority queues
+using JSON such that a queue can be shared between processes, items are
+stored within the queue as raw JSON
+*/
+package server
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+)
+
+// methods is the http method required by each operation
+var methods = map[string]string{
+	OperationEnqueue:         http.MethodPost,
+	OperationEnqueueMultiple: http.MethodPost,
+	OperationDequeue:         http.MethodPost,
+	OperationDequeueMultiple: http.MethodPost,
+	OperationFlush:           http.MethodPost,
+	OperationPeek:            http.MethodGet,
+	OperationLength:          http.MethodGet,
+	OperationCapacity:        http.MethodGet,
+	OperationResize:          http.MethodPost,
+	OperationWrappers:        http.MethodGet,
+	OperationReprioritize:    http.MethodPost,
+}
+
+type server struct {
+	sync.RWMutex
+	queues map[string]Queue
+	config Configuration
+}
+
+// New can be used to create a server for the given queues (keyed by name), a
+// Configuration can optionally be provided as a parameter
+func New(queues map[string]Queue, parameters ...interface{}) Server {
+	s := &server{queues: make(map[string]Queue)}
+	for _, parameter := range parameters {
+		switch p := parameter.(type) {
+		case Configuration:
+			s.config = p
+		case *Configuration:
+			s.config = *p
+		}
+	}
+	if s.config.MaxWait <= 0 {
+		s.config.MaxWait = DefaultMaxWait
+	}
+	for name, queue := range queues {
+		s.queues[name] = queue
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+// marshalItems will encode the items as JSON, items that were enqueued
+// through the server are already raw JSON
+func marshalItems(items []interface{}) ([]json.RawMessage, error) {
+	raw := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		bytes, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, bytes)
+	}
+	return raw, nil
+}
+
+// parseN will parse the n query parameter, it defaults to 1
+func parseN(r *http.Request) (int, error) {
+	value := r.URL.Query().Get(ParameterN)
+	if value == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", ParameterN, value)
+	}
+	return n, nil
+}
+
+// parseWait will parse the wait query parameter, it's limited to
+// the configured maximum wait
+func (s *server) parseWait(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get(ParameterWait)
+	if value == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil || wait < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", ParameterWait, value)
+	}
+	if wait > s.config.MaxWait {
+		wait = s.config.MaxWait
+	}
+	return wait, nil
+}
+
+// poll will execute fn until it returns true, waiting for an item to be
+// enqueued in between; it will stop waiting once the wait has elapsed,
+// the request is cancelled or the queue is closed
+func poll(r *http.Request, queue Queue, wait time.Duration, fn func() bool) {
+	if fn() || wait <= 0 {
+		return
+	}
+	tWait := time.NewTimer(wait)
+	defer tWait.Stop()
+	for {
+		signal := queue.GetSignalIn()
+		if signal == nil {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-tWait.C:
+			fn()
+			return
+		case <-signal:
+		}
+		if fn() {
+			return
+		}
+	}
+}
+
+func (s *server) Add(name string, queue Queue) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.queues[name] = queue
+}
+
+func (s *server) Remove(name string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.queues, name)
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	root := strings.TrimSuffix(PathQueues, "/")
+	if r.URL.Path != root && !strings.HasPrefix(r.URL.Path, PathQueues) {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, root), "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		s.RLock()
+		names := make([]string, 0, len(s.queues))
+		for name := range s.queues {
+			names = append(names, name)
+		}
+		s.RUnlock()
+		sort.Strings(names)
+		writeJSON(w, http.StatusOK, QueuesResponse{Queues: names})
+		return
+	}
+	name, operation, _ := strings.Cut(path, "/")
+	s.RLock()
+	queue, ok := s.queues[name]
+	s.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("queue %q not found", name))
+		return
+	}
+	method, ok := methods[operation]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("operation %q not found", operation))
+		return
+	}
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	switch operation {
+	case OperationEnqueue:
+		s.enqueue(w, r, queue)
+	case OperationEnqueueMultiple:
+		s.enqueueMultiple(w, r, queue)
+	case OperationDequeue:
+		s.dequeue(w, r, queue)
+	case OperationDequeueMultiple:
+		s.dequeueMultiple(w, r, queue)
+	case OperationFlush:
+		s.items(w, queue.Flush())
+	case OperationPeek:
+		if r.URL.Query().Get(ParameterN) == "" {
+			s.items(w, queue.Peek())
+			return
+		}
+		n, err := parseN(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.items(w, queue.PeekFromHead(n))
+	case OperationLength:
+		writeJSON(w, http.StatusOK, LengthResponse{Length: queue.Length()})
+	case OperationCapacity:
+		writeJSON(w, http.StatusOK, CapacityResponse{Capacity: queue.Capacity()})
+	case OperationResize:
+		var request ResizeRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.items(w, queue.Resize(request.Size))
+	case OperationWrappers:
+		s.wrappers(w, r, queue)
+	case OperationReprioritize:
+		var request ReprioritizeRequest
+
+		reprioritizer, ok := queue.(goqueuepriority.Reprioritizer)
+		if !ok {
+			writeError(w, http.StatusNotImplemented, errors.New("queue can't be reprioritized"))
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		count := reprioritizer.Reprioritize(request.From, request.To)
+		writeJSON(w, http.StatusOK, ReprioritizeResponse{Count: count})
+	}
+}
+
+func (s *server) wrappers(w http.ResponseWriter, r *http.Request, queue Queue) {
+	peeker, ok := queue.(goqueuepriority.WrapperPeeker)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, errors.New("queue can't peek wrappers"))
+		return
+	}
+	wrappers := peeker.PeekWrappers()
+	if r.URL.Query().Get(ParameterN) != "" {
+		n, err := parseN(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if n < len(wrappers) {
+			wrappers = wrappers[:n]
+		}
+	}
+	response := WrappersResponse{Wrappers: make([]Wrapper, 0, len(wrappers))}
+	for _, wrapper := range wrappers {
+		item, err := json.Marshal(wrapper.Item)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		response.Wrappers = append(response.Wrappers, Wrapper{
+			Priority:   wrapper.Priority,
+			Key:        wrapper.Key,
+			EnqueuedAt: wrapper.EnqueuedAt,
+			Attempts:   wrapper.Attempts,
+			Tenant:     wrapper.Tenant,
+			Partition:  wrapper.Partition,
+			Item:       item,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *server) items(w http.ResponseWriter, items []interface{}) {
+	raw, err := marshalItems(items)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ItemsResponse{Items: raw})
+}
+
+func (s *server) enqueue(w http.ResponseWriter, r *http.Request, queue Queue) {
+	var request EnqueueRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(request.Item) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("item is required"))
+		return
+	}
+	overflow := queue.PriorityEnqueue(request.Item, request.Priority)
+	writeJSON(w, http.StatusOK, EnqueueResponse{Overflow: overflow})
+}
+
+func (s *server) enqueueMultiple(w http.ResponseWriter, r *http.Request, queue Queue) {
+	var request EnqueueMultipleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	items := make([]interface{}, 0, len(request.Items))
+	for _, item := range request.Items {
+		items = append(items, item)
+	}
+	remaining, overflow := queue.PriorityEnqueueMultiple(items, request.Priorities...)
+	raw, err := marshalItems(remaining)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, EnqueueMultipleResponse{Remaining: raw, Overflow: overflow})
+}
+
+func (s *server) dequeue(w http.ResponseWriter, r *http.Request, queue Queue) {
+	var item interface{}
+	var underflow bool
+
+	wait, err := s.parseWait(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	poll(r, queue, wait, func() bool {
+		item, underflow = queue.Dequeue()
+		return !underflow
+	})
+	if underflow {
+		writeJSON(w, http.StatusOK, DequeueResponse{Underflow: true})
+		return
+	}
+	raw, err := json.Marshal(item)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, DequeueResponse{Item: raw})
+}
+
+func (s *server) dequeueMultiple(w http.ResponseWriter, r *http.Request, queue Queue) {
+	var items []interface{}
+
+	n, err := parseN(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	wait, err := s.parseWait(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	poll(r, queue, wait, func() bool {
+		items = queue.DequeueMultiple(n)
+		return len(items) > 0
+	})
+	s.items(w, items)
+}
@@ -0,0 +1,118 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	goqueuepriorityfinite "github.com/antonio-alexander/go-queue-priority/finite"
+	server "github.com/antonio-alexander/go-queue-priority/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func do(t *testing.T, method, url, body string, response interface{}) int {
+	request, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	assert.Nil(t, err)
+	httpResponse, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	defer httpResponse.Body.Close()
+	if response != nil {
+		assert.Nil(t, json.NewDecoder(httpResponse.Body).Decode(response))
+	}
+	return httpResponse.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	q := goqueuepriorityfinite.New(3)
+	defer q.Close()
+	s := server.New(map[string]server.Queue{"jobs": q}, server.Configuration{
+		MaxWait: time.Second,
+	})
+	httpServer := httptest.NewServer(s)
+	defer httpServer.Close()
+	url := httpServer.URL + server.PathQueues + "jobs/"
+
+	//validate that queues can be listed
+	var queues server.QueuesResponse
+	assert.Equal(t, http.StatusOK, do(t, http.MethodGet, httpServer.URL+"/queues", "", &queues))
+	assert.Equal(t, []string{"jobs"}, queues.Queues)
+
+	//validate that items are enqueued with priority and can be peeked
+	var enqueue server.EnqueueResponse
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationEnqueue, `{"item":"low","priority":1}`, &enqueue))
+	assert.False(t, enqueue.Overflow)
+	var enqueueMultiple server.EnqueueMultipleResponse
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationEnqueueMultiple, `{"items":["high",{"a":1}],"priorities":[3,2]}`, &enqueueMultiple))
+	assert.False(t, enqueueMultiple.Overflow)
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationEnqueue, `{"item":"overflow","priority":1}`, &enqueue))
+	assert.True(t, enqueue.Overflow)
+	var items server.ItemsResponse
+	assert.Equal(t, http.StatusOK, do(t, http.MethodGet, url+server.OperationPeek, "", &items))
+	assert.Equal(t, []json.RawMessage{json.RawMessage(`"high"`), json.RawMessage(`{"a":1}`), json.RawMessage(`"low"`)}, items.Items)
+	assert.Equal(t, http.StatusOK, do(t, http.MethodGet, url+server.OperationPeek+"?n=1", "", &items))
+	assert.Equal(t, []json.RawMessage{json.RawMessage(`"high"`)}, items.Items)
+	var length server.LengthResponse
+	assert.Equal(t, http.StatusOK, do(t, http.MethodGet, url+server.OperationLength, "", &length))
+	assert.Equal(t, 3, length.Length)
+
+	//validate that wrappers can be peeked and items reprioritized
+	var wrappers server.WrappersResponse
+	assert.Equal(t, http.StatusOK, do(t, http.MethodGet, url+server.OperationWrappers+"?n=2", "", &wrappers))
+	if assert.Len(t, wrappers.Wrappers, 2) {
+		assert.Equal(t, 3, wrappers.Wrappers[0].Priority)
+		assert.Equal(t, json.RawMessage(`"high"`), wrappers.Wrappers[0].Item)
+		assert.NotZero(t, wrappers.Wrappers[0].EnqueuedAt)
+	}
+	var reprioritize server.ReprioritizeResponse
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationReprioritize, `{"from":2,"to":4}`, &reprioritize))
+	assert.Equal(t, 1, reprioritize.Count)
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationReprioritize, `{"from":4,"to":2}`, &reprioritize))
+	assert.Equal(t, 1, reprioritize.Count)
+
+	//validate that items are dequeued in priority order
+	var dequeue server.DequeueResponse
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationDequeue, "", &dequeue))
+	assert.Equal(t, json.RawMessage(`"high"`), dequeue.Item)
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationDequeueMultiple+"?n=2", "", &items))
+	assert.Equal(t, []json.RawMessage{json.RawMessage(`{"a":1}`), json.RawMessage(`"low"`)}, items.Items)
+	dequeue = server.DequeueResponse{}
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationDequeue, "", &dequeue))
+	assert.True(t, dequeue.Underflow)
+
+	//validate that a dequeue will wait for an item to be enqueued
+	// and that it won't wait longer than the maximum wait
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		q.PriorityEnqueue(json.RawMessage(`"waited"`), 1)
+	}()
+	dequeue = server.DequeueResponse{}
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationDequeue+"?wait=5s", "", &dequeue))
+	assert.Equal(t, json.RawMessage(`"waited"`), dequeue.Item)
+	tStart := time.Now()
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationDequeueMultiple+"?wait=1m", "", &items))
+	assert.Empty(t, items.Items)
+	assert.Less(t, time.Since(tStart), 5*time.Second)
+
+	//validate capacity and resize
+	var capacity server.CapacityResponse
+	assert.Equal(t, http.StatusOK, do(t, http.MethodGet, url+server.OperationCapacity, "", &capacity))
+	assert.Equal(t, 3, capacity.Capacity)
+	assert.Equal(t, http.StatusOK, do(t, http.MethodPost, url+server.OperationResize, `{"size":5}`, &items))
+	assert.Equal(t, http.StatusOK, do(t, http.MethodGet, url+server.OperationCapacity, "", &capacity))
+	assert.Equal(t, 5, capacity.Capacity)
+
+	//validate errors
+	assert.Equal(t, http.StatusNotFound, do(t, http.MethodGet, httpServer.URL+server.PathQueues+"missing/length", "", nil))
+	assert.Equal(t, http.StatusNotFound, do(t, http.MethodGet, url+"missing", "", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, do(t, http.MethodGet, url+server.OperationEnqueue, "", nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, http.MethodPost, url+server.OperationEnqueue, `{`, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, http.MethodPost, url+server.OperationDequeue+"?wait=soon", "", nil))
+
+	//validate that queues can be removed
+	s.Remove("jobs")
+	assert.Equal(t, http.StatusNotFound, do(t, http.MethodGet, url+server.OperationLength, "", nil))
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	finite "github.com/antonio-alexander/go-queue/finite"
+)
+
+// DefaultMaxWait is the maximum amount of time a dequeue will wait
+// for an item if no maximum is configured
+const DefaultMaxWait time.Duration = 30 * time.Second
+
+// PathQueues is the path under which queues are served, the operation on
+// a queue is served at PathQueues + name + "/" + operation
+const PathQueues string = "/queues/"
+
+// These are the operations that can be performed on a queue
+const (
+	OperationEnqueue         string = "enqueue"
+	OperationEnqueueMultiple string = "enqueue-multiple"
+	OperationDequeue         string = "dequeue"
+	OperationDequeueMultiple string = "dequeue-multiple"
+	OperationFlush           string = "flush"
+	OperationPeek            string = "peek"
+	OperationLength          string = "length"
+	OperationCapacity        string = "capacity"
+	OperationResize          string = "resize"
+	OperationWrappers        string = "wrappers"
+	OperationReprioritize    string = "reprioritize"
+)
+
+// These are the query parameters that can be provided to operations,
+// wait applies to dequeue and dequeue-multiple while n applies to
+// dequeue-multiple, peek and wrappers
+const (
+	ParameterWait string = "wait"
+	ParameterN    string = "n"
+)
+
+// Queue describes the functionality a queue must provide to be served, the
+// wrappers and reprioritize operations are only available if the queue also
+// implements goqueuepriority.WrapperPeeker and goqueuepriority.Reprioritizer
+// respectively
+type Queue interface {
+	goqueue.Dequeuer
+	goqueue.Peeker
+	goqueue.Length
+	goqueue.Event
+	goqueuepriority.PriorityEnqueuer
+	finite.Capacity
+	finite.Resizer
+}
+
+// Server describes an http handler that serves queues by name
+type Server interface {
+	http.Handler
+
+	//Add will serve the queue with the given name, replacing any
+	// queue with the same name
+	Add(name string, queue Queue)
+
+	//Remove will stop serving the queue with the given name
+	Remove(name string)
+}
+
+// Configuration can be provided to New to configure the server
+type Configuration struct {
+	//MaxWait limits how long a dequeue can wait for an item
+	MaxWait time.Duration
+}
+
+// EnqueueRequest is the body of an enqueue request
+type EnqueueRequest struct {
+	Item     json.RawMessage `json:"item"`
+	Priority int             `json:"priority"`
+}
+
+// EnqueueResponse is the body of an enqueue response
+type EnqueueResponse struct {
+	Overflow bool `json:"overflow"`
+}
+
+// EnqueueMultipleRequest is the body of an enqueue multiple request, if the
+// number of priorities doesn't match the number of items, the first priority
+// is used for all items
+type EnqueueMultipleRequest struct {
+	Items      []json.RawMessage `json:"items"`
+	Priorities []int             `json:"priorities,omitempty"`
+}
+
+// EnqueueMultipleResponse is the body of an enqueue multiple response,
+// it contains the items that couldn't be enqueued
+type EnqueueMultipleResponse struct {
+	Remaining []json.RawMessage `json:"remaining,omitempty"`
+	Overflow  bool              `json:"overflow"`
+}
+
+// DequeueResponse is the body of a dequeue response
+type DequeueResponse struct {
+	Item      json.RawMessage `json:"item,omitempty"`
+	Underflow bool            `json:"underflow"`
+}
+
+// ItemsResponse is the body of a dequeue multiple, flush, peek or
+// resize response
+type ItemsResponse struct {
+	Items []json.RawMessage `json:"items"`
+}
+
+// LengthResponse is the body of a length response
+type LengthResponse struct {
+	Length int `json:"length"`
+}
+
+// CapacityResponse is the body of a capacity response
+type CapacityResponse struct {
+	Capacity int `json:"capacity"`
+}
+
+// ResizeRequest is the body of a resize request
+type ResizeRequest struct {
+	Size int `json:"size"`
+}
+
+// QueuesResponse is the body of the response when listing queues
+type QueuesResponse struct {
+	Queues []string `json:"queues"`
+}
+
+// Wrapper describes an item in the queue along with its context
+type Wrapper struct {
+	Priority   int             `json:"priority"`
+	Key        []int           `json:"key,omitempty"`
+	EnqueuedAt int64           `json:"enqueued_at"`
+	Attempts   int             `json:"attempts,omitempty"`
+	Tenant     string          `json:"tenant,omitempty"`
+	Partition  string          `json:"partition,omitempty"`
+	Item       json.RawMessage `json:"item"`
+}
+
+// WrappersResponse is the body of a wrappers response
+type WrappersResponse struct {
+	Wrappers []Wrapper `json:"wrappers"`
+}
+
+// ReprioritizeRequest is the body of a reprioritize request
+type ReprioritizeRequest struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// ReprioritizeResponse is the body of a reprioritize response
+type ReprioritizeResponse struct {
+	Count int `json:"count"`
+}
+
+// ErrorResponse is the body of the response when an error occurs
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
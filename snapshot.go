@@ -0,0 +1,100 @@
+package priority
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SnapshotVersion is the version of the snapshot format written
+// by WriteSnapshot
+const SnapshotVersion int = 1
+
+// ErrSnapshotOverflow is returned when a snapshot has more items than
+// can fit within the queue it's being restored to
+var ErrSnapshotOverflow = errors.New("snapshot has more items than the capacity of the queue")
+
+// Snapshotter can be used to serialize the contents of a queue and restore
+// them; the priority, time enqueued and order of items are preserved
+type Snapshotter interface {
+	//Snapshot will write the contents of the queue to the writer
+	Snapshot(w io.Writer) (err error)
+
+	//Restore will replace the contents of the queue with the contents
+	// of the snapshot read from the reader
+	Restore(r io.Reader) (err error)
+}
+
+type snapshot struct {
+	Version  int               `json:"version"`
+	Wrappers []snapshotWrapper `json:"wrappers"`
+}
+
+type snapshotWrapper struct {
+	Priority   int             `json:"priority"`
+	Key        []int           `json:"key,omitempty"`
+	EnqueuedAt int64           `json:"enqueued_at"`
+	Attempts   int             `json:"attempts,omitempty"`
+	Tenant     string          `json:"tenant,omitempty"`
+	Partition  string          `json:"partition,omitempty"`
+	Type       string          `json:"type,omitempty"`
+	Item       json.RawMessage `json:"item"`
+}
+
+// WriteSnapshot can be used to write the wrappers to the writer using the
+// current snapshot version; items whose type has been registered (see
+// RegisterType) will retain their type when read
+func WriteSnapshot(w io.Writer, wrappers []*Wrapper) error {
+	s := snapshot{
+		Version:  SnapshotVersion,
+		Wrappers: make([]snapshotWrapper, 0, len(wrappers)),
+	}
+	for _, wrapper := range wrappers {
+		name, item, err := marshalItem(wrapper.Item)
+		if err != nil {
+			return err
+		}
+		s.Wrappers = append(s.Wrappers, snapshotWrapper{
+			Priority:   wrapper.Priority,
+			Key:        wrapper.Key,
+			EnqueuedAt: wrapper.EnqueuedAt,
+			Attempts:   wrapper.Attempts,
+			Tenant:     wrapper.Tenant,
+			Partition:  wrapper.Partition,
+			Type:       name,
+			Item:       item,
+		})
+	}
+	return json.NewEncoder(w).Encode(s)
+}
+
+// ReadSnapshot can be used to read wrappers written by WriteSnapshot, they'll
+// be returned in the order they were written
+func ReadSnapshot(r io.Reader) ([]*Wrapper, error) {
+	var s snapshot
+
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version != SnapshotVersion {
+		return nil, fmt.Errorf("priority: unsupported snapshot version %d", s.Version)
+	}
+	wrappers := make([]*Wrapper, 0, len(s.Wrappers))
+	for _, w := range s.Wrappers {
+		item, err := unmarshalItem(w.Type, w.Item)
+		if err != nil {
+			return nil, err
+		}
+		wrappers = append(wrappers, &Wrapper{
+			Priority:   w.Priority,
+			Key:        w.Key,
+			EnqueuedAt: w.EnqueuedAt,
+			Attempts:   w.Attempts,
+			Tenant:     w.Tenant,
+			Partition:  w.Partition,
+			Item:       item,
+		})
+	}
+	return wrappers, nil
+}
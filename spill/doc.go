@@ -0,0 +1,11 @@
+// Copyright 2023 antonio-alexander. All rights reserved.
+// Use of this source code is governed by an MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package priorityspill provides a hybrid priority queue that keeps the items with
+the highest priority in memory and spills items with a lower priority to segment
+files on disk rather than overflowing; as items are dequeued, spilled items are
+paged back into memory such that items are always dequeued in priority order
+*/
+package priorityspill
@@ -0,0 +1,620 @@
+package priorityspill
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	priorityqueue "github.com/antonio-alexander/go-queue-priority"
+	internal "github.com/antonio-alexander/go-queue-priority/internal"
+)
+
+// segmentPattern is the pattern used to name segment files
+const segmentPattern string = "segment-*.spill"
+
+// lockName is the name of the file used to lock dir
+const lockName string = "spill.lock"
+
+type queueSpill struct {
+	sync.RWMutex
+	dir          string
+	config       Configuration
+	size         int
+	sequence     uint64
+	segments     uint64
+	active       *segment
+	lock         *os.File
+	memory       []*entry
+	disk         []*spilled
+	signalIn     chan struct{}
+	signalOut    chan struct{}
+	observer     priorityqueue.Observer
+	priorityFunc priorityqueue.PriorityFunc
+	codec        priorityqueue.Codec
+	closed       bool
+}
+
+// Open can be used to create a hybrid priority queue that keeps up to size items
+// in memory and spills the remaining items to segment files within dir; dir is
+// locked until the queue is closed, so it can't be shared by queues (opening a
+// queue with a dir that's in use returns ErrLocked) and segment files left
+// behind in dir are removed. The optional parameters can be used to further
+// configure the queue:
+//   - Configuration: configures the size of segments and how many items can be
+//     spilled to disk
+//   - priorityqueue.Observer: will be notified as items move through the queue
+//   - priorityqueue.PriorityFunc: determines the priority of items enqueued using
+//     Enqueue, otherwise items that implement priorityqueue.Prioritizer provide their own
+//     priority (items enqueued using PriorityEnqueue without a priority have the
+//     default priority)
+//   - priorityqueue.Codec: used to encode spilled items, by default
+//     priorityqueue.JSONCodec is used so items whose type is registered (see
+//     priorityqueue.RegisterType) retain their type when paged back in
+func Open(dir string, size int, parameters ...interface{}) (interface {
+	goqueue.Owner
+	goqueue.GarbageCollecter
+	goqueue.Length
+	goqueue.Event
+	goqueue.Peeker
+	goqueue.Dequeuer
+	goqueue.Enqueuer
+	priorityqueue.PriorityEnqueuer
+	priorityqueue.WrapperPeeker
+	priorityqueue.Snapshotter
+}, error) {
+	if size < 1 {
+		size = 1
+	}
+	q := &queueSpill{
+		dir:   dir,
+		size:  size,
+		codec: priorityqueue.JSONCodec{},
+	}
+	for _, parameter := range parameters {
+		switch p := parameter.(type) {
+		case Configuration:
+			q.config = p
+		case *Configuration:
+			q.config = *p
+		case priorityqueue.Observer:
+			q.observer = p
+		case priorityqueue.PriorityFunc:
+			q.priorityFunc = p
+		case func(item interface{}) int:
+			q.priorityFunc = p
+		case priorityqueue.Codec:
+			q.codec = p
+		}
+	}
+	if q.config.SegmentSize <= 0 {
+		q.config.SegmentSize = DefaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(filepath.Join(dir, lockName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := internal.Lock(lock); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, segmentPattern))
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			lock.Close()
+			return nil, err
+		}
+	}
+	q.lock = lock
+	q.memory = make([]*entry, 0, size)
+	q.signalIn = make(chan struct{}, size)
+	q.signalOut = make(chan struct{}, size)
+	return q, nil
+}
+
+// less will determine if the item with wrapper a and id aID should be dequeued
+// before the item with wrapper b and id bID, equal items are ordered by their id
+func less(a, b *priorityqueue.Wrapper, aID, bID uint64) bool {
+	if priorityqueue.Less(a, b) {
+		return true
+	}
+	if priorityqueue.Less(b, a) {
+		return false
+	}
+	return aID < bID
+}
+
+// insert will place the entry into memory such that it remains sorted
+func (q *queueSpill) insert(e *entry) {
+	index := sort.Search(len(q.memory), func(i int) bool {
+		return less(e.wrapper, q.memory[i].wrapper, e.id, q.memory[i].id)
+	})
+	q.memory = append(q.memory, nil)
+	copy(q.memory[index+1:], q.memory[index:])
+	q.memory[index] = e
+}
+
+// spill will append the entry to the active segment (creating a new segment if
+// the active segment is full) and add it to the index of spilled items
+func (q *queueSpill) spill(e *entry) error {
+	if q.config.DiskCapacity > 0 && len(q.disk) >= q.config.DiskCapacity {
+		return errors.New("disk capacity reached")
+	}
+	envelope, err := priorityqueue.MarshalWrapper(q.codec, e.wrapper)
+	if err != nil {
+		return err
+	}
+	if q.active == nil || (q.active.size > 0 && q.active.size+int64(len(envelope)) > q.config.SegmentSize) {
+		q.segments++
+		path := filepath.Join(q.dir, fmt.Sprintf("segment-%020d.spill", q.segments))
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		q.active = &segment{file: file}
+	}
+	if _, err := q.active.file.WriteAt(envelope, q.active.size); err != nil {
+		return err
+	}
+	wrapper := *e.wrapper
+	wrapper.Item = nil
+	s := &spilled{
+		id:      e.id,
+		wrapper: &wrapper,
+		segment: q.active,
+		offset:  q.active.size,
+		length:  len(envelope),
+	}
+	q.active.size += int64(len(envelope))
+	q.active.live++
+	index := sort.Search(len(q.disk), func(i int) bool {
+		return less(s.wrapper, q.disk[i].wrapper, s.id, q.disk[i].id)
+	})
+	q.disk = append(q.disk, nil)
+	copy(q.disk[index+1:], q.disk[index:])
+	q.disk[index] = s
+	return nil
+}
+
+// load will read the spilled item from its segment
+func (q *queueSpill) load(s *spilled) (*priorityqueue.Wrapper, error) {
+	envelope := make([]byte, s.length)
+	if _, err := s.segment.file.ReadAt(envelope, s.offset); err != nil {
+		return nil, err
+	}
+	return priorityqueue.UnmarshalWrapper(q.codec, envelope)
+}
+
+// release will mark the spilled item as no longer on disk, once a segment has
+// no items remaining it's removed (or truncated if it's the active segment)
+func (q *queueSpill) release(s *spilled) {
+	s.segment.live--
+	if s.segment.live > 0 {
+		return
+	}
+	if s.segment == q.active {
+		if err := q.active.file.Truncate(0); err == nil {
+			q.active.size = 0
+		}
+		return
+	}
+	q.discard(s.segment)
+}
+
+// discard will close and remove the segment file
+func (q *queueSpill) discard(s *segment) {
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
+
+// fill will page spilled items back into memory until memory is full or
+// there are no more items on disk; since memory always contains the items
+// with the highest priority, paged in items are appended to memory
+func (q *queueSpill) fill() {
+	for len(q.memory) < q.size && len(q.disk) > 0 {
+		s := q.disk[0]
+		q.disk[0] = nil
+		q.disk = q.disk[1:]
+		wrapper, err := q.load(s)
+		q.release(s)
+		if err != nil {
+			//KIM: if the item can't be read from disk, there's no way to
+			// return it so it's treated as if it was evicted
+			if q.observer != nil {
+				q.observer.Evicted(s.wrapper)
+			}
+			continue
+		}
+		q.memory = append(q.memory, &entry{id: s.id, wrapper: wrapper})
+	}
+}
+
+// remove will remove up to n items from the front of the queue, paging
+// spilled items back into memory as needed
+func (q *queueSpill) remove(n int) []*priorityqueue.Wrapper {
+	var wrappers []*priorityqueue.Wrapper
+
+	for len(wrappers) < n && len(q.memory) > 0 {
+		count := n - len(wrappers)
+		if count > len(q.memory) {
+			count = len(q.memory)
+		}
+		for _, e := range q.memory[:count] {
+			wrappers = append(wrappers, e.wrapper)
+		}
+		copy(q.memory, q.memory[count:])
+		for i := len(q.memory) - count; i < len(q.memory); i++ {
+			q.memory[i] = nil
+		}
+		q.memory = q.memory[:len(q.memory)-count]
+		q.fill()
+	}
+	return wrappers
+}
+
+// enqueue will place the item in memory if it's one of the items with the
+// highest priority, otherwise it's spilled to disk; it will return true if
+// the item couldn't be enqueued
+func (q *queueSpill) enqueue(wrapper *priorityqueue.Wrapper) bool {
+	if q.closed {
+		if q.observer != nil {
+			q.observer.Overflowed(wrapper.Priority)
+		}
+		return true
+	}
+	e := &entry{id: q.sequence + 1, wrapper: wrapper}
+
+	//KIM: items are only spilled to disk once memory is full, so if
+	// memory isn't full, there are no items on disk
+	switch {
+	case len(q.memory) < q.size:
+		q.insert(e)
+	case less(e.wrapper, q.memory[len(q.memory)-1].wrapper, e.id, q.memory[len(q.memory)-1].id):
+		tail := q.memory[len(q.memory)-1]
+		if err := q.spill(tail); err != nil {
+			if q.observer != nil {
+				q.observer.Overflowed(wrapper.Priority)
+			}
+			return true
+		}
+		q.memory[len(q.memory)-1] = nil
+		q.memory = q.memory[:len(q.memory)-1]
+		q.insert(e)
+	default:
+		if err := q.spill(e); err != nil {
+			if q.observer != nil {
+				q.observer.Overflowed(wrapper.Priority)
+			}
+			return true
+		}
+	}
+	q.sequence = e.id
+	if q.observer != nil {
+		q.observer.Enqueued(wrapper)
+	}
+	return false
+}
+
+func (q *queueSpill) dequeued(wrappers ...*priorityqueue.Wrapper) {
+	if q.observer != nil {
+		for _, wrapper := range wrappers {
+			q.observer.Dequeued(wrapper)
+		}
+	}
+}
+
+// wrappers will return the wrappers of the items in memory followed by
+// the items on disk, spilled items that can't be read are omitted
+func (q *queueSpill) wrappers() []*priorityqueue.Wrapper {
+	wrappers := make([]*priorityqueue.Wrapper, 0, len(q.memory)+len(q.disk))
+	for _, e := range q.memory {
+		wrappers = append(wrappers, e.wrapper)
+	}
+	for _, s := range q.disk {
+		if wrapper, err := q.load(s); err == nil {
+			wrappers = append(wrappers, wrapper)
+		}
+	}
+	return wrappers
+}
+
+// head will return the wrappers of up to n items from the front
+// of the queue without removing them
+func (q *queueSpill) head(n int) []*priorityqueue.Wrapper {
+	if n > len(q.memory)+len(q.disk) {
+		n = len(q.memory) + len(q.disk)
+	}
+	wrappers := make([]*priorityqueue.Wrapper, 0, n)
+	for _, e := range q.memory {
+		if len(wrappers) >= n {
+			return wrappers
+		}
+		wrappers = append(wrappers, e.wrapper)
+	}
+	for _, s := range q.disk {
+		if len(wrappers) >= n {
+			break
+		}
+		if wrapper, err := q.load(s); err == nil {
+			wrappers = append(wrappers, wrapper)
+		}
+	}
+	return wrappers
+}
+
+// Close will stop the queue, the items in memory and on disk are returned and
+// the segment files are removed
+func (q *queueSpill) Close() []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.closed {
+		return nil
+	}
+	remainingElements := internal.Items(q.wrappers())
+	for _, s := range q.disk {
+		q.release(s)
+	}
+	if q.active != nil {
+		q.discard(q.active)
+	}
+	if q.signalIn != nil {
+		select {
+		default:
+			close(q.signalIn)
+		case <-q.signalIn:
+		}
+	}
+	if q.signalOut != nil {
+		select {
+		default:
+			close(q.signalOut)
+		case <-q.signalOut:
+		}
+	}
+	_ = q.lock.Close()
+	q.memory, q.disk, q.active, q.signalIn, q.signalOut = nil, nil, nil, nil, nil
+	q.closed = true
+	return remainingElements
+}
+
+func (q *queueSpill) GarbageCollect() {
+	q.Lock()
+	defer q.Unlock()
+
+	memory := make([]*entry, len(q.memory), q.size)
+	copy(memory, q.memory)
+	q.memory = memory
+	disk := make([]*spilled, len(q.disk))
+	copy(disk, q.disk)
+	q.disk = disk
+}
+
+func (q *queueSpill) GetSignalIn() <-chan struct{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.signalIn
+}
+
+func (q *queueSpill) GetSignalOut() <-chan struct{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.signalOut
+}
+
+func (q *queueSpill) Dequeue() (interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	wrappers := q.remove(1)
+	if len(wrappers) == 0 {
+		return nil, true
+	}
+	q.dequeued(wrappers...)
+	internal.SendSignal(q.signalOut)
+	return wrappers[0].Item, false
+}
+
+func (q *queueSpill) DequeueMultiple(n int) []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	wrappers := q.remove(n)
+	if len(wrappers) == 0 {
+		return nil
+	}
+	q.dequeued(wrappers...)
+	internal.SendSignal(q.signalOut)
+	return internal.Items(wrappers)
+}
+
+func (q *queueSpill) Flush() []interface{} {
+	q.Lock()
+	defer q.Unlock()
+
+	wrappers := q.remove(len(q.memory) + len(q.disk))
+	if len(wrappers) == 0 {
+		return nil
+	}
+	q.dequeued(wrappers...)
+	internal.SendSignal(q.signalOut)
+	return internal.Items(wrappers)
+}
+
+func (q *queueSpill) Enqueue(item interface{}) bool {
+	return q.PriorityEnqueue(item, priorityqueue.ItemPriority(item, q.priorityFunc))
+}
+
+func (q *queueSpill) EnqueueMultiple(items []interface{}) ([]interface{}, bool) {
+	return q.PriorityEnqueueMultiple(items, priorityqueue.ItemPriorities(items, q.priorityFunc)...)
+}
+
+func (q *queueSpill) PriorityEnqueue(item interface{}, priorities ...int) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	priority := priorityqueue.DefaultPriority
+	if len(priorities) > 0 {
+		priority = priorities[0]
+	}
+	if overflow := q.enqueue(&priorityqueue.Wrapper{
+		Item:       item,
+		Priority:   priority,
+		EnqueuedAt: time.Now().UnixNano(),
+	}); overflow {
+		return true
+	}
+	internal.SendSignal(q.signalIn)
+	return false
+}
+
+func (q *queueSpill) PriorityEnqueueMultiple(items []interface{}, priorities ...int) ([]interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	var itemEnqueued bool
+
+	defer func() {
+		if itemEnqueued {
+			internal.SendSignal(q.signalIn)
+		}
+	}()
+	if len(priorities) != len(items) {
+		priority := priorityqueue.DefaultPriority
+		if len(priorities) > 0 {
+			priority = priorities[0]
+		}
+		priorities = make([]int, 0, len(items))
+		for range items {
+			priorities = append(priorities, priority)
+		}
+	}
+	for i, item := range items {
+		if overflow := q.enqueue(&priorityqueue.Wrapper{
+			Item:       item,
+			Priority:   priorities[i],
+			EnqueuedAt: time.Now().UnixNano(),
+		}); overflow {
+			return items[i:], true
+		}
+		itemEnqueued = true
+	}
+	return nil, false
+}
+
+func (q *queueSpill) Length() int {
+	q.RLock()
+	defer q.RUnlock()
+
+	return len(q.memory) + len(q.disk)
+}
+
+func (q *queueSpill) Peek() []interface{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	items := internal.Items(q.wrappers())
+	if items == nil {
+		return []interface{}{}
+	}
+	return items
+}
+
+func (q *queueSpill) PeekHead() (interface{}, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if len(q.memory) <= 0 {
+		return nil, true
+	}
+	return q.memory[0].wrapper.Item, false
+}
+
+func (q *queueSpill) PeekFromHead(n int) []interface{} {
+	q.RLock()
+	defer q.RUnlock()
+
+	if len(q.memory) == 0 {
+		return nil
+	}
+	items := internal.Items(q.head(n))
+	if items == nil {
+		return []interface{}{}
+	}
+	return items
+}
+
+func (q *queueSpill) PeekWrappers() []*priorityqueue.Wrapper {
+	q.RLock()
+	defer q.RUnlock()
+
+	wrappers := q.wrappers()
+	for i, w := range wrappers {
+		wrapper := *w
+		wrappers[i] = &wrapper
+	}
+	return wrappers
+}
+
+func (q *queueSpill) PeekHeadWrapper() (*priorityqueue.Wrapper, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if len(q.memory) <= 0 {
+		return nil, true
+	}
+	wrapper := *q.memory[0].wrapper
+	return &wrapper, false
+}
+
+func (q *queueSpill) Snapshot(w io.Writer) error {
+	q.RLock()
+	defer q.RUnlock()
+
+	return priorityqueue.WriteSnapshot(w, q.wrappers())
+}
+
+func (q *queueSpill) Restore(r io.Reader) error {
+	wrappers, err := priorityqueue.ReadSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if q.closed {
+		return os.ErrClosed
+	}
+	if q.config.DiskCapacity > 0 && len(wrappers) > q.size+q.config.DiskCapacity {
+		return priorityqueue.ErrSnapshotOverflow
+	}
+	for _, wrapper := range q.remove(len(q.memory) + len(q.disk)) {
+		if q.observer != nil {
+			q.observer.Evicted(wrapper)
+		}
+	}
+	for _, wrapper := range wrappers {
+		if overflow := q.enqueue(wrapper); overflow {
+			return priorityqueue.ErrSnapshotOverflow
+		}
+	}
+	if len(wrappers) > 0 {
+		internal.SendSignal(q.signalIn)
+	}
+	return nil
+}
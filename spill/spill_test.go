@@ -0,0 +1,221 @@
+package priorityspill_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	goqueue "github.com/antonio-alexander/go-queue"
+	goqueuepriority "github.com/antonio-alexander/go-queue-priority"
+	goqueuepriorityspill "github.com/antonio-alexander/go-queue-priority/spill"
+
+	goqueuepriorityfinite_tests "github.com/antonio-alexander/go-queue-priority/finite/tests"
+	goqueue_tests "github.com/antonio-alexander/go-queue/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	mustTimeout time.Duration = time.Second
+	mustRate    time.Duration = time.Millisecond
+)
+
+func open(t *testing.T, size int, parameters ...interface{}) interface {
+	goqueue.Owner
+	goqueue.Length
+	goqueue.Event
+	goqueue.Peeker
+	goqueue.Dequeuer
+	goqueue.Enqueuer
+	goqueue.GarbageCollecter
+	goqueuepriority.PriorityEnqueuer
+	goqueuepriority.WrapperPeeker
+	goqueuepriority.Snapshotter
+} {
+	q, err := goqueuepriorityspill.Open(t.TempDir(), size, parameters...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("Test Dequeue", goqueue_tests.TestDequeue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Dequeue Event", goqueue_tests.TestDequeueEvent(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Dequeuer
+		goqueue.Enqueuer
+		goqueue.Event
+		goqueue.Owner
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Dequeue Multiple", goqueue_tests.TestDequeueMultiple(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Flush", goqueue_tests.TestFlush(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Peek", goqueue_tests.TestPeek(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Peeker
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Peek From Head", goqueue_tests.TestPeekFromHead(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Peeker
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Length", goqueue_tests.TestLength(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+		goqueue.Length
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Garbage Collect", goqueue_tests.TestGarbageCollect(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.GarbageCollecter
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Queue", goqueue_tests.TestQueue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Asynchronous", goqueue_tests.TestAsync(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Enqueuer
+		goqueue.Dequeuer
+	} {
+		return open(t, size)
+	}))
+}
+
+func TestPrioritySpillQueue(t *testing.T) {
+	t.Run("Test Priority Enqueue", goqueuepriorityfinite_tests.TestPriorityEnqueue(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueuepriority.PriorityEnqueuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Priority Enqueue Event", goqueuepriorityfinite_tests.TestPriorityEnqueueEvent(t, mustRate, mustTimeout, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueue.Event
+		goqueuepriority.PriorityEnqueuer
+	} {
+		return open(t, size)
+	}))
+	t.Run("Test Snapshot", goqueuepriorityfinite_tests.TestSnapshot(t, func(size int) interface {
+		goqueue.Owner
+		goqueue.Dequeuer
+		goqueuepriority.PriorityEnqueuer
+		goqueuepriority.WrapperPeeker
+		goqueuepriority.Snapshotter
+	} {
+		//KIM: the disk capacity is bounded so half of the items are
+		// spilled and the queue can overflow when restoring
+		return open(t, size/2, goqueuepriorityspill.Configuration{
+			DiskCapacity: size - size/2,
+		})
+	}))
+}
+
+func TestSpill(t *testing.T) {
+	dir := t.TempDir()
+	q, err := goqueuepriorityspill.Open(dir, 2, goqueuepriorityspill.Configuration{
+		SegmentSize:  64,
+		DiskCapacity: 6,
+	})
+	assert.Nil(t, err)
+
+	//enqueue more items than fit in memory and validate that they're
+	// spilled to disk and counted
+	for i, priority := range []int{1, 2, 1, 3, 2, 0, 3, 1} {
+		assert.False(t, q.PriorityEnqueue(float64(i), priority))
+	}
+	assert.True(t, q.PriorityEnqueue(float64(8), 0))
+	assert.Equal(t, 8, q.Length())
+	paths, err := filepath.Glob(filepath.Join(dir, "*.spill"))
+	assert.Nil(t, err)
+	assert.Greater(t, len(paths), 1)
+	expected := []interface{}{
+		float64(3), float64(6), float64(1), float64(4),
+		float64(0), float64(2), float64(7), float64(5),
+	}
+	assert.Equal(t, expected, q.Peek())
+	assert.Equal(t, expected[:3], q.PeekFromHead(3))
+
+	//validate that items are dequeued in priority order across
+	// memory and disk, including items enqueued after spilling
+	item, underflow := q.Dequeue()
+	assert.False(t, underflow)
+	assert.Equal(t, expected[0], item)
+	assert.False(t, q.PriorityEnqueue(float64(9), 2))
+	assert.Equal(t, []interface{}{float64(6), float64(1), float64(4), float64(9)}, q.DequeueMultiple(4))
+	assert.Equal(t, 4, q.Length())
+	assert.Equal(t, expected[4:], q.Flush())
+	assert.Equal(t, 0, q.Length())
+
+	//validate that the segment files are removed once the items are
+	// paged back in and when the queue is closed, only the file used
+	// to lock the directory remains
+	assert.False(t, q.PriorityEnqueue(float64(10), 1))
+	assert.False(t, q.PriorityEnqueue(float64(11), 1))
+	assert.False(t, q.PriorityEnqueue(float64(12), 1))
+	assert.Equal(t, []interface{}{float64(10), float64(11), float64(12)}, q.Close())
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "spill.lock", entries[0].Name())
+	}
+}
+
+// TestSpillLock is meant to confirm that a directory can't be used by more
+// than one queue at a time, so queues can't remove each other's segments
+func TestSpillLock(t *testing.T) {
+	dir := t.TempDir()
+	q, err := goqueuepriorityspill.Open(dir, 1, goqueuepriorityspill.Configuration{
+		SegmentSize: 64,
+	})
+	assert.Nil(t, err)
+	assert.False(t, q.PriorityEnqueue(float64(1), 1))
+	assert.False(t, q.PriorityEnqueue(float64(2), 1))
+	_, err = goqueuepriorityspill.Open(dir, 1)
+	assert.ErrorIs(t, err, goqueuepriorityspill.ErrLocked)
+	assert.Equal(t, []interface{}{float64(1), float64(2)}, q.Flush())
+
+	//validate that the directory can be used once the queue is closed
+	q.Close()
+	q, err = goqueuepriorityspill.Open(dir, 1)
+	assert.Nil(t, err)
+	q.Close()
+}
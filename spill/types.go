@@ -0,0 +1,54 @@
+package priorityspill
+
+import (
+	"os"
+
+	priorityqueue "github.com/antonio-alexander/go-queue-priority"
+	internal "github.com/antonio-alexander/go-queue-priority/internal"
+)
+
+// ErrLocked is returned by Open when dir is already used by another
+// queue (e.g. in another process)
+var ErrLocked = internal.ErrLocked
+
+// DefaultSegmentSize is the size (in bytes) after which a new segment
+// file is created if no segment size is configured
+const DefaultSegmentSize int64 = 4 * 1024 * 1024
+
+// Configuration can be provided to Open to configure how items are
+// spilled to disk
+type Configuration struct {
+	//SegmentSize is the size (in bytes) after which a new segment
+	// file is created
+	SegmentSize int64
+
+	//DiskCapacity is the maximum number of items that can be spilled
+	// to disk, once reached the queue will overflow; zero means the
+	// number of items spilled to disk is unbounded
+	DiskCapacity int
+}
+
+// entry is an item in memory along with the sequence used to order
+// items with the same priority and enqueue time
+type entry struct {
+	id      uint64
+	wrapper *priorityqueue.Wrapper
+}
+
+// spilled is an item that's been spilled to disk, the wrapper is kept
+// (without the item) so spilled items can be ordered
+type spilled struct {
+	id      uint64
+	wrapper *priorityqueue.Wrapper
+	segment *segment
+	offset  int64
+	length  int
+}
+
+// segment is a file that spilled items are appended to, once none of
+// its items remain on disk it's removed
+type segment struct {
+	file *os.File
+	size int64
+	live int
+}
@@ -6,15 +6,21 @@ const DefaultPriority int = 0
 
 // Wrapper is used to provide context to items that are placed into
 // the queue, each item that you add to the priority queue is placed
-// within this wrapper
+// within this wrapper; Key contains any additional levels of priority
+// that are compared (in order) when items have the same Priority
 type Wrapper struct {
 	Priority   int         `json:"priority"`
+	Key        []int       `json:"key,omitempty"`
 	EnqueuedAt int64       `json:"enqueued_at"`
+	Attempts   int         `json:"attempts,omitempty"`
+	Tenant     string      `json:"tenant,omitempty"`
+	Partition  string      `json:"partition,omitempty"`
 	Item       interface{} `json:"item"`
 }
 
 // PriorityEnqueuer describes an interface for enqueueing items
-// with priority
+// with priority, items enqueued without a priority have the
+// DefaultPriority
 type PriorityEnqueuer interface {
 	//PriorityEnqueue can be used to enqueue a single item
 	// with an optional priority; this can be a drop-in replacement
@@ -28,18 +34,306 @@ type PriorityEnqueuer interface {
 	PriorityEnqueueMultiple(items []interface{}, priority ...int) (itemsRemaining []interface{}, overflow bool)
 }
 
+// TailDequeuer describes an interface for accessing the tail of the queue
+// (e.g. to shed load by removing the least important items); the tail is
+// the item with the lowest priority that was enqueued most recently, so
+// items are removed from the tail in the reverse of the order they would
+// otherwise be dequeued (when dequeued strictly by priority). Items are
+// removed from the tail directly, bypassing any scheduling or active queue
+// management used by Dequeue (e.g. the finite queue's Weights, round robin
+// between tenants and CoDel)
+type TailDequeuer interface {
+	//DequeueTail can be used to dequeue the item at the tail of the
+	// queue, underflow will be true if the queue is empty
+	DequeueTail() (item interface{}, underflow bool)
+
+	//DequeueTailMultiple can be used to dequeue up to n items from the
+	// tail of the queue, starting with the item at the tail
+	DequeueTailMultiple(n int) (items []interface{})
+
+	//PeekTail can be used to read the item at the tail of the queue without
+	// removing it, underflow will be true if the queue is empty
+	PeekTail() (item interface{}, underflow bool)
+
+	//PeekFromTail can be used to read up to n items from the tail of the
+	// queue without removing them, starting with the item at the tail
+	PeekFromTail(n int) (items []interface{})
+}
+
+// ConditionalDequeuer describes an interface for dequeueing (or peeking) only
+// the items that match a condition (e.g. only urgent items), items that don't
+// match remain in the queue in the same order; matching items are dequeued in
+// order of priority and then in the order they were enqueued, bypassing any
+// scheduling or active queue management used by Dequeue (e.g. the finite
+// queue's Weights, round robin between tenants and CoDel)
+type ConditionalDequeuer interface {
+	//DequeueAtLeast can be used to dequeue the next item with a priority
+	// of at least minPriority, underflow will be true if there's no such item
+	DequeueAtLeast(minPriority int) (item interface{}, underflow bool)
+
+	//DequeueMultipleAtLeast can be used to dequeue up to n items with a
+	// priority of at least minPriority
+	DequeueMultipleAtLeast(n, minPriority int) (items []interface{})
+
+	//DequeueIf can be used to dequeue every item for which fn returns true
+	DequeueIf(fn func(item interface{}, priority int) bool) (items []interface{})
+
+	//PeekAtLeast can be used to read the next item with a priority of at
+	// least minPriority, underflow will be true if there's no such item
+	PeekAtLeast(minPriority int) (item interface{}, underflow bool)
+
+	//PeekMultipleAtLeast can be used to read up to n items with a priority
+	// of at least minPriority
+	PeekMultipleAtLeast(n, minPriority int) (items []interface{})
+
+	//PeekIf can be used to read every item for which fn returns true
+	PeekIf(fn func(item interface{}, priority int) bool) (items []interface{})
+}
+
+// KeyEnqueuer describes an interface for enqueueing items with a composite
+// priority (e.g. severity, then customer tier, then deadline); keys are
+// compared lexicographically, greater values are dequeued first and any
+// levels missing from a key are treated as DefaultPriority, so a key with
+// a single element is the same as enqueueing with that priority
+type KeyEnqueuer interface {
+	//KeyEnqueue can be used to enqueue a single item with a key
+	KeyEnqueue(item interface{}, key ...int) (overflow bool)
+
+	//KeyEnqueueMultiple can be used to enqueue zero or more items, a
+	// single key can be provided OR a key for each item can be provided
+	KeyEnqueueMultiple(items []interface{}, keys ...[]int) (itemsRemaining []interface{}, overflow bool)
+}
+
+// WrapperDequeuer can be used to dequeue an item along with its wrapper (e.g.
+// to learn its priority and number of attempts)
+type WrapperDequeuer interface {
+	//DequeueWrapper can be used to dequeue a single item along with its
+	// wrapper, underflow will be true if the queue is empty
+	DequeueWrapper() (wrapper *Wrapper, underflow bool)
+}
+
+// WrapperEnqueuer can be used to enqueue an item along with its wrapper, the
+// priority (and key), attempts and tenant of the wrapper are retained while
+// the time it was enqueued is set when it's enqueued
+type WrapperEnqueuer interface {
+	//EnqueueWrapper can be used to enqueue a single item along with
+	// its wrapper
+	EnqueueWrapper(wrapper *Wrapper) (overflow bool)
+}
+
+// Rescorer can be used to re-evaluate the priority of items already in the
+// queue (e.g. when their priority depends on state that changes over time)
+type Rescorer interface {
+	//Rescore will re-evaluate the priority of every item, it returns the
+	// number of items whose priority changed
+	Rescore() (n int)
+}
+
+// Prioritizer can be implemented by items to provide their own priority, it's
+// used to determine the priority of items enqueued without a priority (e.g.
+// using Enqueue)
+type Prioritizer interface {
+	Priority() (priority int)
+}
+
+// PriorityFunc can be provided to a queue on creation to determine the
+// priority of items enqueued without a priority (e.g. using Enqueue), it
+// takes precedence over items that implement Prioritizer
+type PriorityFunc func(item interface{}) (priority int)
+
+// TenantEnqueuer describes an interface for enqueueing items on behalf of
+// a tenant (or flow) with priority; items with the same priority are
+// dequeued in round robin between tenants
+type TenantEnqueuer interface {
+	//TenantEnqueue can be used to enqueue a single item for a tenant
+	// with an optional priority
+	TenantEnqueue(tenant string, item interface{}, priority ...int) (overflow bool)
+
+	//TenantEnqueueMultiple can be used to enqueue zero or more items for
+	// a tenant with an optional priority; a single priority can be provided
+	// OR a priority for each item can be provided
+	TenantEnqueueMultiple(tenant string, items []interface{}, priority ...int) (itemsRemaining []interface{}, overflow bool)
+}
+
+// WrapperPeeker can be used to non-destructively read the wrappers within
+// the queue (in the order they would be dequeued); the wrappers returned are
+// copies and modifying them won't affect the queue
+type WrapperPeeker interface {
+	//PeekWrappers will return all of the wrappers in the queue
+	PeekWrappers() (wrappers []*Wrapper)
+
+	//PeekHeadWrapper will return the wrapper at the front of the queue,
+	// underflow will be true if the queue is empty
+	PeekHeadWrapper() (wrapper *Wrapper, underflow bool)
+}
+
+// WrapperHeadPeeker can be used to peek the wrappers at the front of the
+// queue without copying every wrapper in the queue
+type WrapperHeadPeeker interface {
+	//PeekWrappersFromHead will return up to n wrappers from the front
+	// of the queue
+	PeekWrappersFromHead(n int) (wrappers []*Wrapper)
+}
+
+// Reprioritizer can be used to change the priority of items already in the
+// queue; items retain when they were enqueued, so they're ordered by age
+// amongst the items that already have their new priority
+type Reprioritizer interface {
+	//Reprioritize will change the priority of every item with the priority
+	// from to the priority to, it returns the number of items changed
+	Reprioritize(from, to int) (n int)
+}
+
+// RejectionObserver can optionally be implemented by an Observer to be
+// notified when items are rejected by an admission policy, otherwise items
+// that are rejected are reported as overflowed
+type RejectionObserver interface {
+	//Rejected is called for every item that couldn't be enqueued
+	// because it was rejected by an admission policy
+	Rejected(priority int)
+}
+
+// ReprioritizationObserver can optionally be implemented by an Observer to be
+// notified when the priority of an item already in the queue is changed (e.g.
+// using Reprioritize), otherwise the item is reported as evicted with its old
+// priority and then enqueued with its new priority
+type ReprioritizationObserver interface {
+	//Reprioritized is called for every item whose priority is changed
+	// from the priority from to the wrapper's priority
+	Reprioritized(wrapper *Wrapper, from int)
+}
+
+// Observer can be provided to a queue on creation to be notified whenever
+// items move through the queue; the functions are executed while the queue
+// is locked so they should return quickly and never call back into the
+// queue. Items that remain in the queue when it's closed aren't reported
+type Observer interface {
+	//Enqueued is called for every item successfully enqueued
+	Enqueued(wrapper *Wrapper)
+
+	//Dequeued is called for every item that is removed from the
+	// queue and returned to the caller
+	Dequeued(wrapper *Wrapper)
+
+	//Overflowed is called for every item that couldn't be enqueued
+	// because the queue was full
+	Overflowed(priority int)
+
+	//Evicted is called for every item that is removed from the queue
+	// without being returned to a consumer (e.g. lossy enqueue or resize)
+	Evicted(wrapper *Wrapper)
+}
+
+// Less can be used to determine if wrapper a should be dequeued before
+// wrapper b; items with a greater priority are dequeued first and items
+// with the same priority are dequeued in the order they were enqueued
+func Less(a, b *Wrapper) bool {
+	if c := Compare(a, b); c != 0 {
+		return c > 0
+	}
+	return a.EnqueuedAt < b.EnqueuedAt
+}
+
+// Compare can be used to compare the priority (and key) of wrapper a with
+// wrapper b, it returns a positive number if a has a greater priority, a
+// negative number if b has a greater priority and zero if they're the same
+func Compare(a, b *Wrapper) int {
+	if a.Priority != b.Priority {
+		if a.Priority > b.Priority {
+			return 1
+		}
+		return -1
+	}
+	n := len(a.Key)
+	if len(b.Key) > n {
+		n = len(b.Key)
+	}
+	for i := 0; i < n; i++ {
+		x, y := level(a.Key, i), level(b.Key, i)
+		if x > y {
+			return 1
+		}
+		if x < y {
+			return -1
+		}
+	}
+	return 0
+}
+
+// level will return the level of the key, missing levels are
+// treated as DefaultPriority
+func level(key []int, i int) int {
+	if i < len(key) {
+		return key[i]
+	}
+	return DefaultPriority
+}
+
+// SplitKey can be used to split a composite key into its priority and any
+// additional levels; an empty key has a priority of DefaultPriority
+func SplitKey(key []int) (int, []int) {
+	if len(key) == 0 {
+		return DefaultPriority, nil
+	}
+	if len(key) == 1 {
+		return key[0], nil
+	}
+	return key[0], append([]int(nil), key[1:]...)
+}
+
+// ItemPriority can be used to determine the priority of an item enqueued
+// without a priority; fn is used if provided, otherwise the priority of
+// items that implement Prioritizer is used, otherwise DefaultPriority
+func ItemPriority(item interface{}, fn PriorityFunc) int {
+	if fn != nil {
+		return fn(item)
+	}
+	if prioritizer, ok := item.(Prioritizer); ok {
+		return prioritizer.Priority()
+	}
+	return DefaultPriority
+}
+
+// ItemPriorities can be used to determine the priority of each item using
+// ItemPriority
+func ItemPriorities(items []interface{}, fn PriorityFunc) []int {
+	priorities := make([]int, 0, len(items))
+	for _, item := range items {
+		priorities = append(priorities, ItemPriority(item, fn))
+	}
+	return priorities
+}
+
 type ByPriority []*Wrapper
 
 func (b ByPriority) Len() int           { return len(b) }
 func (b ByPriority) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
-func (b ByPriority) Less(i, j int) bool { return b[i].Priority > b[j].Priority }
+func (b ByPriority) Less(i, j int) bool { return Compare(b[i], b[j]) > 0 }
 
 type ByEnqueuedAt []*Wrapper
 
 func (b ByEnqueuedAt) Len() int { return len(b) }
 func (b ByEnqueuedAt) Swap(i, j int) {
-	if b[i].Priority == b[j].Priority {
+	if Compare(b[i], b[j]) == 0 {
 		b[i], b[j] = b[j], b[i]
 	}
 }
 func (b ByEnqueuedAt) Less(i, j int) bool { return b[i].EnqueuedAt < b[j].EnqueuedAt }
+
+// NotifyReprioritized can be used to notify the observer (if any) that the
+// priority of the wrapper was changed from the priority from; observers that
+// don't implement ReprioritizationObserver are notified that the item was
+// evicted with its old priority and then enqueued with its new priority
+func NotifyReprioritized(observer Observer, wrapper *Wrapper, from int) {
+	if observer == nil {
+		return
+	}
+	if o, ok := observer.(ReprioritizationObserver); ok {
+		o.Reprioritized(wrapper, from)
+		return
+	}
+	old := *wrapper
+	old.Priority = from
+	observer.Evicted(&old)
+	observer.Enqueued(wrapper)
+}